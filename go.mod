@@ -1,6 +1,6 @@
 module github.com/adnsv/go-db3
 
-go 1.19
+go 1.23
 
 require (
 	golang.org/x/exp v0.0.0-20221111094246-ab4555d3164f
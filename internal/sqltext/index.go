@@ -0,0 +1,61 @@
+package sqltext
+
+// CreateIndex is the parsed form of a 'create index' statement.
+type CreateIndex struct {
+	Unique      bool
+	IfNotExists bool
+	Schema      string
+	Name        string
+	Table       string
+	Columns     []*IndexedColumn
+	Where       string
+}
+
+// ParseCreateIndex parses a 'create index' statement.
+func ParseCreateIndex(src string) (*CreateIndex, error) {
+	p, err := NewParser(src)
+	if err != nil {
+		return nil, err
+	}
+	ci, err := p.CreateIndex()
+	if err != nil {
+		return nil, err
+	}
+	if !p.AtEnd() {
+		return nil, p.Errorf("unexpected trailing input")
+	}
+	return ci, nil
+}
+
+// CreateIndex parses a 'create index' statement.
+func (p *Parser) CreateIndex() (*CreateIndex, error) {
+	ci := &CreateIndex{}
+	if err := p.Expect("create"); err != nil {
+		return nil, err
+	}
+	ci.Unique = p.Accept("unique")
+	if err := p.Expect("index"); err != nil {
+		return nil, err
+	}
+	ci.IfNotExists = p.Accept("if", "not", "exists")
+	var err error
+	ci.Schema, ci.Name, err = p.QualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.Expect("on"); err != nil {
+		return nil, err
+	}
+	if ci.Table, err = p.Name(); err != nil {
+		return nil, err
+	}
+	if ci.Columns, err = p.IndexedColumns(); err != nil {
+		return nil, err
+	}
+	if p.Accept("where") {
+		if ci.Where, err = p.SkipExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return ci, nil
+}
@@ -0,0 +1,86 @@
+package sqltext
+
+import (
+	"strconv"
+	"strings"
+)
+
+// LiteralKind classifies the sql text of a default value, see ParseLiteral.
+type LiteralKind int
+
+const (
+	RawLiteral LiteralKind = iota // strings, blobs, reals and expressions
+	NullLiteral
+	IntLiteral
+	TrueLiteral
+	FalseLiteral
+	CurrentTimeLiteral
+	CurrentDateLiteral
+	CurrentTimestampLiteral
+)
+
+// Literal is the classified sql text of a default value.
+type Literal struct {
+	Kind LiteralKind
+	Int  int64  // value of IntLiteral
+	Text string // text of RawLiteral, string literals are double quoted
+}
+
+// ParseLiteral classifies the sql text of a default value.
+func ParseLiteral(s string) Literal {
+	switch strings.ToLower(s) {
+	case "null":
+		return Literal{Kind: NullLiteral}
+	case "current_time":
+		return Literal{Kind: CurrentTimeLiteral}
+	case "current_date":
+		return Literal{Kind: CurrentDateLiteral}
+	case "current_timestamp":
+		return Literal{Kind: CurrentTimestampLiteral}
+	case "true":
+		return Literal{Kind: TrueLiteral}
+	case "false":
+		return Literal{Kind: FalseLiteral}
+	default:
+		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return Literal{Kind: IntLiteral, Int: v}
+		} else {
+			return Literal{Kind: RawLiteral, Text: Requote(s)}
+		}
+	}
+}
+
+// Requote replaces the single quotes around a string literal with double
+// quotes, escaping the quotes within accordingly. Other text is returned as
+// is.
+func Requote(s string) string {
+	n := len(s)
+	if n >= 2 && s[0] == '\'' && s[n-1] == '\'' {
+		t := strings.ReplaceAll(s[1:n-1], "''", "'")
+		return `"` + strings.ReplaceAll(t, `"`, `""`) + `"`
+	} else {
+		return s
+	}
+}
+
+// ForeignKeyAction normalizes the sql text of a foreign key action, 'no
+// action' and unknown actions produce an empty string.
+func ForeignKeyAction(s string) string {
+	switch a := strings.ToLower(s); a {
+	case "restrict", "set null", "set default", "cascade":
+		return a
+	default:
+		return ""
+	}
+}
+
+// ConflictAction normalizes the sql text of a conflict resolution, abort and
+// unknown resolutions produce an empty string.
+func ConflictAction(s string) string {
+	switch a := strings.ToLower(s); a {
+	case "rollback", "fail", "ignore", "replace":
+		return a
+	default:
+		return ""
+	}
+}
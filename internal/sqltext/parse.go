@@ -0,0 +1,717 @@
+package sqltext
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CreateTable is the parsed form of a 'create table' statement.
+type CreateTable struct {
+	Temporary    bool
+	IfNotExists  bool
+	Schema       string
+	Name         string
+	Columns      []*ColumnDef
+	Constraints  []*Constraint
+	WithoutRowID bool
+	Strict       bool
+}
+
+// ColumnDef is a column definition within a 'create table' statement.
+type ColumnDef struct {
+	Name        string
+	Type        string
+	Constraints []*Constraint
+}
+
+type ConstraintKind int
+
+const (
+	PrimaryKey ConstraintKind = iota + 1
+	NotNull
+	Null
+	Unique
+	Check
+	Default
+	Collate
+	ForeignKey
+	Generated
+)
+
+// Constraint is a column or table constraint. Only the fields relevant to its
+// Kind are populated.
+type Constraint struct {
+	Kind          ConstraintKind
+	Name          string
+	Columns       []*IndexedColumn // table-level primary key, unique and foreign key
+	Desc          bool             // column-level primary key
+	AutoIncrement bool
+	OnConflict    string
+	Expr          string // check, default and generated expressions
+	Collation     string
+	Stored        bool
+	References    *References
+}
+
+// IndexedColumn is an entry within primary key, unique or index column
+// lists. For expressions, Name is empty and Expr contains the expression
+// text.
+type IndexedColumn struct {
+	Name      string
+	Expr      string
+	Collation string
+	Desc      bool
+}
+
+// References is the foreign key clause.
+type References struct {
+	Table      string
+	Columns    []string
+	OnDelete   string
+	OnUpdate   string
+	Match      string
+	Deferrable Deferrable
+}
+
+// Deferrable is the 'deferrable' clause of a foreign key.
+type Deferrable string
+
+const (
+	NotDeferrable      Deferrable = ""          // no clause or 'not deferrable'
+	InitiallyImmediate Deferrable = "immediate" // 'deferrable [initially immediate]'
+	InitiallyDeferred  Deferrable = "deferred"  // 'deferrable initially deferred'
+)
+
+// Column returns the column definition with the specified name (compared
+// case-insensitively).
+func (ct *CreateTable) Column(name string) *ColumnDef {
+	for _, c := range ct.Columns {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Find returns the first constraint of the specified kind.
+func (cd *ColumnDef) Find(kind ConstraintKind) *Constraint {
+	for _, c := range cd.Constraints {
+		if c.Kind == kind {
+			return c
+		}
+	}
+	return nil
+}
+
+// Parser is a recursive descent parser over a token stream.
+type Parser struct {
+	src  string
+	toks []Token
+	pos  int
+}
+
+// NewParser tokenizes src and prepares it for parsing.
+func NewParser(src string) (*Parser, error) {
+	tt, err := Tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{src: src, toks: tt}, nil
+}
+
+// Peek returns the current token.
+func (p *Parser) Peek() Token { return p.toks[p.pos] }
+
+// PeekAt returns the token at the specified offset from the current one.
+func (p *Parser) PeekAt(offset int) Token {
+	i := p.pos + offset
+	if i >= len(p.toks) {
+		i = len(p.toks) - 1
+	}
+	return p.toks[i]
+}
+
+// Next returns the current token and advances.
+func (p *Parser) Next() Token {
+	t := p.toks[p.pos]
+	if t.Kind != EOF {
+		p.pos++
+	}
+	return t
+}
+
+// AtEnd returns true if there are no more tokens other than an optional
+// trailing semicolon.
+func (p *Parser) AtEnd() bool {
+	t := p.Peek()
+	return t.Kind == EOF || (t.IsPunct(";") && p.PeekAt(1).Kind == EOF)
+}
+
+// Accept consumes the sequence of keywords if present.
+func (p *Parser) Accept(keywords ...string) bool {
+	for i, kw := range keywords {
+		if !p.PeekAt(i).Is(kw) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+// AcceptPunct consumes the punctuation if present.
+func (p *Parser) AcceptPunct(s string) bool {
+	if p.Peek().IsPunct(s) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// Expect consumes the sequence of keywords or fails.
+func (p *Parser) Expect(keywords ...string) error {
+	if !p.Accept(keywords...) {
+		return p.Errorf("expected %s", strings.Join(keywords, " "))
+	}
+	return nil
+}
+
+// ExpectPunct consumes the punctuation or fails.
+func (p *Parser) ExpectPunct(s string) error {
+	if !p.AcceptPunct(s) {
+		return p.Errorf("expected '%s'", s)
+	}
+	return nil
+}
+
+// Errorf produces an error that refers to the current token.
+func (p *Parser) Errorf(format string, args ...any) error {
+	t := p.Peek()
+	at := "end of input"
+	if t.Kind != EOF {
+		at = fmt.Sprintf("'%s' at offset %d", t.Text, t.Pos)
+	}
+	return fmt.Errorf("%s, got %s", fmt.Sprintf(format, args...), at)
+}
+
+// Name consumes a name token and returns its unquoted value.
+func (p *Parser) Name() (string, error) {
+	t := p.Peek()
+	if !t.IsName() {
+		return "", p.Errorf("expected name")
+	}
+	p.pos++
+	return t.Value(), nil
+}
+
+// QualifiedName consumes an optionally schema-qualified name.
+func (p *Parser) QualifiedName() (schema, name string, err error) {
+	name, err = p.Name()
+	if err != nil {
+		return
+	}
+	if p.AcceptPunct(".") {
+		schema = name
+		name, err = p.Name()
+	}
+	return
+}
+
+// Pos returns the position of the current token, see Text.
+func (p *Parser) Pos() int { return p.pos }
+
+// Text returns the source text between two token positions.
+func (p *Parser) Text(from, to int) string {
+	if from >= to {
+		return ""
+	}
+	return p.src[p.toks[from].Pos:p.toks[to-1].End]
+}
+
+// Rest returns the source text from the current token to the end, without
+// a trailing semicolon.
+func (p *Parser) Rest() string {
+	end := len(p.toks) - 1
+	if end > p.pos && p.toks[end-1].IsPunct(";") {
+		end--
+	}
+	s := p.Text(p.pos, end)
+	p.pos = end
+	return s
+}
+
+// SkipStatement advances to the semicolon that terminates the statement, or
+// to the end of input. It returns the source text of the skipped part.
+func (p *Parser) SkipStatement() string {
+	start := p.pos
+	for t := p.Peek(); t.Kind != EOF && !t.IsPunct(";"); t = p.Peek() {
+		p.pos++
+	}
+	return p.Text(start, p.pos)
+}
+
+// SkipExpr advances over an expression, stopping at the first top-level
+// comma, closing parenthesis, semicolon, or at any of the specified
+// keywords. It returns the source text of the expression.
+func (p *Parser) SkipExpr(stop ...string) (string, error) {
+	start := p.pos
+	depth := 0
+	case_depth := 0
+loop:
+	for {
+		t := p.Peek()
+		switch {
+		case t.Kind == EOF:
+			break loop
+		case t.IsPunct("("):
+			depth++
+		case t.IsPunct(")"):
+			if depth == 0 {
+				break loop
+			}
+			depth--
+		case depth == 0 && (t.IsPunct(",") || t.IsPunct(";")):
+			break loop
+		case t.Is("case"):
+			case_depth++
+		case t.Is("end") && case_depth > 0:
+			case_depth--
+		case depth == 0 && case_depth == 0:
+			for _, kw := range stop {
+				if t.Is(kw) {
+					break loop
+				}
+			}
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.Errorf("expected expression")
+	}
+	return p.Text(start, p.pos), nil
+}
+
+// ParenExpr consumes a parenthesized expression and returns the text inside
+// the parentheses.
+func (p *Parser) ParenExpr() (string, error) {
+	if err := p.ExpectPunct("("); err != nil {
+		return "", err
+	}
+	s, err := p.SkipExpr()
+	if err != nil {
+		return "", err
+	}
+	return s, p.ExpectPunct(")")
+}
+
+// ParseCreateTable parses a 'create table' statement.
+func ParseCreateTable(src string) (*CreateTable, error) {
+	p, err := NewParser(src)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := p.CreateTable()
+	if err != nil {
+		return nil, err
+	}
+	if !p.AtEnd() {
+		return nil, p.Errorf("unexpected trailing input")
+	}
+	return ct, nil
+}
+
+// CreateTable parses a 'create table' statement.
+func (p *Parser) CreateTable() (*CreateTable, error) {
+	ct := &CreateTable{}
+	if err := p.Expect("create"); err != nil {
+		return nil, err
+	}
+	ct.Temporary = p.Accept("temp") || p.Accept("temporary")
+	if err := p.Expect("table"); err != nil {
+		return nil, err
+	}
+	ct.IfNotExists = p.Accept("if", "not", "exists")
+	var err error
+	ct.Schema, ct.Name, err = p.QualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if p.Peek().Is("as") {
+		return nil, p.Errorf("'create table ... as select' is not supported")
+	}
+	if err := p.ExpectPunct("("); err != nil {
+		return nil, err
+	}
+	for {
+		if is_table_constraint_start(p.Peek()) {
+			c, err := p.table_constraint()
+			if err != nil {
+				return nil, err
+			}
+			ct.Constraints = append(ct.Constraints, c)
+		} else {
+			cd, err := p.column_def()
+			if err != nil {
+				return nil, err
+			}
+			ct.Columns = append(ct.Columns, cd)
+		}
+		if !p.AcceptPunct(",") {
+			break
+		}
+	}
+	if err := p.ExpectPunct(")"); err != nil {
+		return nil, err
+	}
+	for {
+		if p.Accept("without", "rowid") {
+			ct.WithoutRowID = true
+		} else if p.Accept("strict") {
+			ct.Strict = true
+		} else {
+			break
+		}
+		if !p.AcceptPunct(",") {
+			break
+		}
+	}
+	return ct, nil
+}
+
+func is_table_constraint_start(t Token) bool {
+	return t.Is("constraint") || t.Is("primary") || t.Is("unique") ||
+		t.Is("check") || t.Is("foreign")
+}
+
+func is_column_constraint_start(t Token) bool {
+	for _, kw := range []string{"constraint", "primary", "not", "null",
+		"unique", "check", "default", "collate", "references", "generated", "as"} {
+		if t.Is(kw) {
+			return true
+		}
+	}
+	return false
+}
+
+// ColumnDef parses a column definition as used in 'create table' and 'alter
+// table add column' statements.
+func (p *Parser) ColumnDef() (*ColumnDef, error) {
+	return p.column_def()
+}
+
+func (p *Parser) column_def() (*ColumnDef, error) {
+	name, err := p.Name()
+	if err != nil {
+		return nil, err
+	}
+	cd := &ColumnDef{Name: name}
+
+	// type name: a sequence of names, optionally followed by (n) or (n,m)
+	start := p.pos
+	for p.Peek().IsName() && !is_column_constraint_start(p.Peek()) {
+		p.pos++
+	}
+	if p.pos > start && p.AcceptPunct("(") {
+		for {
+			if _, err := p.SkipExpr(); err != nil {
+				return nil, err
+			}
+			if !p.AcceptPunct(",") {
+				break
+			}
+		}
+		if err := p.ExpectPunct(")"); err != nil {
+			return nil, err
+		}
+	}
+	cd.Type = p.Text(start, p.pos)
+
+	for is_column_constraint_start(p.Peek()) {
+		c, err := p.column_constraint()
+		if err != nil {
+			return nil, err
+		}
+		cd.Constraints = append(cd.Constraints, c)
+	}
+	return cd, nil
+}
+
+func (p *Parser) constraint_name() (string, error) {
+	if p.Accept("constraint") {
+		return p.Name()
+	}
+	return "", nil
+}
+
+func (p *Parser) conflict_clause() (string, error) {
+	if !p.Accept("on", "conflict") {
+		return "", nil
+	}
+	t := p.Next()
+	for _, a := range []string{"rollback", "abort", "fail", "ignore", "replace"} {
+		if t.Is(a) {
+			return a, nil
+		}
+	}
+	p.pos--
+	return "", p.Errorf("expected conflict resolution")
+}
+
+func (p *Parser) column_constraint() (*Constraint, error) {
+	var err error
+	c := &Constraint{}
+	if c.Name, err = p.constraint_name(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.Accept("primary", "key"):
+		c.Kind = PrimaryKey
+		if p.Accept("desc") {
+			c.Desc = true
+		} else {
+			p.Accept("asc")
+		}
+		if c.OnConflict, err = p.conflict_clause(); err != nil {
+			return nil, err
+		}
+		c.AutoIncrement = p.Accept("autoincrement")
+
+	case p.Accept("not", "null"):
+		c.Kind = NotNull
+		c.OnConflict, err = p.conflict_clause()
+
+	case p.Accept("null"):
+		c.Kind = Null
+		c.OnConflict, err = p.conflict_clause()
+
+	case p.Accept("unique"):
+		c.Kind = Unique
+		c.OnConflict, err = p.conflict_clause()
+
+	case p.Accept("check"):
+		c.Kind = Check
+		c.Expr, err = p.ParenExpr()
+
+	case p.Accept("default"):
+		c.Kind = Default
+		c.Expr, err = p.default_value()
+
+	case p.Accept("collate"):
+		c.Kind = Collate
+		c.Collation, err = p.Name()
+
+	case p.Peek().Is("references"):
+		c.Kind = ForeignKey
+		c.References, err = p.references()
+
+	case p.Peek().Is("generated") || p.Peek().Is("as"):
+		c.Kind = Generated
+		if p.Accept("generated") {
+			if err = p.Expect("always"); err != nil {
+				return nil, err
+			}
+		}
+		if err = p.Expect("as"); err != nil {
+			return nil, err
+		}
+		if c.Expr, err = p.ParenExpr(); err != nil {
+			return nil, err
+		}
+		if p.Accept("stored") {
+			c.Stored = true
+		} else {
+			p.Accept("virtual")
+		}
+
+	default:
+		return nil, p.Errorf("expected column constraint")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (p *Parser) default_value() (string, error) {
+	start := p.pos
+	t := p.Peek()
+	switch {
+	case t.IsPunct("("):
+		p.Next()
+		if _, err := p.SkipExpr(); err != nil {
+			return "", err
+		}
+		if err := p.ExpectPunct(")"); err != nil {
+			return "", err
+		}
+	case t.IsPunct("-") || t.IsPunct("+"):
+		p.Next()
+		if p.Peek().Kind != Number {
+			return "", p.Errorf("expected number")
+		}
+		p.Next()
+	case t.Kind == Number || t.Kind == String || t.Kind == Blob || t.Kind == Ident || t.Kind == Quoted:
+		p.Next()
+	default:
+		return "", p.Errorf("expected default value")
+	}
+	return p.Text(start, p.pos), nil
+}
+
+func (p *Parser) references() (*References, error) {
+	if err := p.Expect("references"); err != nil {
+		return nil, err
+	}
+	r := &References{}
+	var err error
+	if r.Table, err = p.Name(); err != nil {
+		return nil, err
+	}
+	if p.Peek().IsPunct("(") {
+		if r.Columns, err = p.name_list(); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		switch {
+		case p.Accept("on", "delete"):
+			if r.OnDelete, err = p.fk_action(); err != nil {
+				return nil, err
+			}
+		case p.Accept("on", "update"):
+			if r.OnUpdate, err = p.fk_action(); err != nil {
+				return nil, err
+			}
+		case p.Accept("match"):
+			if r.Match, err = p.Name(); err != nil {
+				return nil, err
+			}
+		default:
+			if p.Accept("not", "deferrable") {
+				p.Accept("initially", "deferred")
+				p.Accept("initially", "immediate")
+			} else if p.Accept("deferrable") {
+				r.Deferrable = InitiallyImmediate
+				if p.Accept("initially", "deferred") {
+					r.Deferrable = InitiallyDeferred
+				} else {
+					p.Accept("initially", "immediate")
+				}
+			}
+			return r, nil
+		}
+	}
+}
+
+func (p *Parser) fk_action() (string, error) {
+	for _, a := range [][]string{
+		{"set", "null"}, {"set", "default"}, {"cascade"}, {"restrict"}, {"no", "action"},
+	} {
+		if p.Accept(a...) {
+			return strings.Join(a, " "), nil
+		}
+	}
+	return "", p.Errorf("expected foreign key action")
+}
+
+func (p *Parser) name_list() ([]string, error) {
+	if err := p.ExpectPunct("("); err != nil {
+		return nil, err
+	}
+	names := []string{}
+	for {
+		n, err := p.Name()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+		if !p.AcceptPunct(",") {
+			break
+		}
+	}
+	return names, p.ExpectPunct(")")
+}
+
+// IndexedColumns parses a parenthesized list of indexed columns.
+func (p *Parser) IndexedColumns() ([]*IndexedColumn, error) {
+	if err := p.ExpectPunct("("); err != nil {
+		return nil, err
+	}
+	cc := []*IndexedColumn{}
+	for {
+		c := &IndexedColumn{}
+		t := p.Peek()
+		next := p.PeekAt(1)
+		if t.IsName() && (next.IsPunct(",") || next.IsPunct(")") ||
+			next.Is("collate") || next.Is("asc") || next.Is("desc")) {
+			c.Name = t.Value()
+			p.Next()
+		} else {
+			expr, err := p.SkipExpr("collate", "asc", "desc")
+			if err != nil {
+				return nil, err
+			}
+			c.Expr = expr
+		}
+		if p.Accept("collate") {
+			var err error
+			if c.Collation, err = p.Name(); err != nil {
+				return nil, err
+			}
+		}
+		if p.Accept("desc") {
+			c.Desc = true
+		} else {
+			p.Accept("asc")
+		}
+		cc = append(cc, c)
+		if !p.AcceptPunct(",") {
+			break
+		}
+	}
+	return cc, p.ExpectPunct(")")
+}
+
+func (p *Parser) table_constraint() (*Constraint, error) {
+	var err error
+	c := &Constraint{}
+	if c.Name, err = p.constraint_name(); err != nil {
+		return nil, err
+	}
+	switch {
+	case p.Accept("primary", "key"):
+		c.Kind = PrimaryKey
+		if c.Columns, err = p.IndexedColumns(); err != nil {
+			return nil, err
+		}
+		c.OnConflict, err = p.conflict_clause()
+
+	case p.Accept("unique"):
+		c.Kind = Unique
+		if c.Columns, err = p.IndexedColumns(); err != nil {
+			return nil, err
+		}
+		c.OnConflict, err = p.conflict_clause()
+
+	case p.Accept("check"):
+		c.Kind = Check
+		c.Expr, err = p.ParenExpr()
+
+	case p.Accept("foreign", "key"):
+		c.Kind = ForeignKey
+		var names []string
+		if names, err = p.name_list(); err != nil {
+			return nil, err
+		}
+		for _, n := range names {
+			c.Columns = append(c.Columns, &IndexedColumn{Name: n})
+		}
+		c.References, err = p.references()
+
+	default:
+		return nil, p.Errorf("expected table constraint")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
@@ -0,0 +1,219 @@
+package sqltext
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseCreateTable(t *testing.T) {
+	tests := []struct {
+		src  string
+		want *CreateTable
+	}{
+		{
+			`create table t (a, b)`,
+			&CreateTable{Name: "t", Columns: []*ColumnDef{{Name: "a"}, {Name: "b"}}},
+		},
+		{
+			`CREATE TEMP TABLE IF NOT EXISTS "main"."my ""t""" ([a b] varchar (10, 2), 'c' unsigned big int);`,
+			&CreateTable{Temporary: true, IfNotExists: true, Schema: "main", Name: `my "t"`, Columns: []*ColumnDef{
+				{Name: "a b", Type: "varchar (10, 2)"},
+				{Name: "c", Type: "unsigned big int"},
+			}},
+		},
+		{
+			`create table t (
+				-- comment with ( and '
+				a int /* ) */ default (abs(-1) + (2 * (3))),
+				b text default 'it''s',
+				c blob default x'00ff',
+				d real default -1.5e3
+			)`,
+			&CreateTable{Name: "t", Columns: []*ColumnDef{
+				{Name: "a", Type: "int", Constraints: []*Constraint{{Kind: Default, Expr: "(abs(-1) + (2 * (3)))"}}},
+				{Name: "b", Type: "text", Constraints: []*Constraint{{Kind: Default, Expr: "'it''s'"}}},
+				{Name: "c", Type: "blob", Constraints: []*Constraint{{Kind: Default, Expr: "x'00ff'"}}},
+				{Name: "d", Type: "real", Constraints: []*Constraint{{Kind: Default, Expr: "-1.5e3"}}},
+			}},
+		},
+		{
+			`create table t (
+				id integer constraint pk primary key desc on conflict replace autoincrement,
+				a not null on conflict fail collate nocase check (a <> ')'),
+				b as (a || ',') stored,
+				c generated always as (case when a then (1) end) virtual
+			) without rowid, strict`,
+			&CreateTable{Name: "t", WithoutRowID: true, Strict: true, Columns: []*ColumnDef{
+				{Name: "id", Type: "integer", Constraints: []*Constraint{
+					{Kind: PrimaryKey, Name: "pk", Desc: true, OnConflict: "replace", AutoIncrement: true}}},
+				{Name: "a", Constraints: []*Constraint{
+					{Kind: NotNull, OnConflict: "fail"},
+					{Kind: Collate, Collation: "nocase"},
+					{Kind: Check, Expr: "a <> ')'"}}},
+				{Name: "b", Constraints: []*Constraint{{Kind: Generated, Expr: "a || ','", Stored: true}}},
+				{Name: "c", Constraints: []*Constraint{{Kind: Generated, Expr: "case when a then (1) end"}}},
+			}},
+		},
+		{
+			`create table t (
+				a references p on delete set null on update cascade deferrable initially deferred,
+				b references q deferrable,
+				primary key (a, b desc) on conflict ignore,
+				constraint u unique (b collate nocase),
+				check ((a > 0) and (b > 0)),
+				foreign key (a, b) references p (x, y) match full not deferrable
+			)`,
+			&CreateTable{Name: "t", Columns: []*ColumnDef{
+				{Name: "a", Constraints: []*Constraint{{Kind: ForeignKey, References: &References{
+					Table: "p", OnDelete: "set null", OnUpdate: "cascade", Deferrable: InitiallyDeferred}}}},
+				{Name: "b", Constraints: []*Constraint{{Kind: ForeignKey, References: &References{
+					Table: "q", Deferrable: InitiallyImmediate}}}},
+			}, Constraints: []*Constraint{
+				{Kind: PrimaryKey, Columns: []*IndexedColumn{{Name: "a"}, {Name: "b", Desc: true}}, OnConflict: "ignore"},
+				{Kind: Unique, Name: "u", Columns: []*IndexedColumn{{Name: "b", Collation: "nocase"}}},
+				{Kind: Check, Expr: "(a > 0) and (b > 0)"},
+				{Kind: ForeignKey, Columns: []*IndexedColumn{{Name: "a"}, {Name: "b"}}, References: &References{
+					Table: "p", Columns: []string{"x", "y"}, Match: "full"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseCreateTable(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %s\nwant %s", tt.src, dump(got), dump(tt.want))
+		}
+	}
+}
+
+func TestParseCreateIndex(t *testing.T) {
+	tests := []struct {
+		src  string
+		want *CreateIndex
+	}{
+		{
+			`create index i on t (a)`,
+			&CreateIndex{Name: "i", Table: "t", Columns: []*IndexedColumn{{Name: "a"}}},
+		},
+		{
+			`create unique index if not exists s.i on t (lower(a) collate nocase desc, (b + 1), "c" asc) where a is not null and (b > 0);`,
+			&CreateIndex{Unique: true, IfNotExists: true, Schema: "s", Name: "i", Table: "t", Columns: []*IndexedColumn{
+				{Expr: "lower(a)", Collation: "nocase", Desc: true},
+				{Expr: "(b + 1)"},
+				{Name: "c"},
+			}, Where: "a is not null and (b > 0)"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseCreateIndex(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %s\nwant %s", tt.src, dump(got), dump(tt.want))
+		}
+	}
+}
+
+func TestParseCreateTrigger(t *testing.T) {
+	tests := []struct {
+		src  string
+		want *CreateTrigger
+	}{
+		{
+			`create trigger tr after update of a, "b" on t for each row when new.a > 0 begin
+				update t set c = case when new.a > 1 then 'end' else 0 end;
+			end`,
+			&CreateTrigger{Name: "tr", Timing: "after", Event: "update", Columns: []string{"a", "b"},
+				Table: "t", ForEachRow: true, When: "new.a > 0",
+				Body: "update t set c = case when new.a > 1 then 'end' else 0 end;"},
+		},
+		{
+			`create temp trigger if not exists tr instead of delete on v begin select 1; end;`,
+			&CreateTrigger{Temporary: true, IfNotExists: true, Name: "tr", Timing: "instead of",
+				Event: "delete", Table: "v", Body: "select 1;"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := ParseCreateTrigger(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %s\nwant %s", tt.src, dump(got), dump(tt.want))
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`create t (a)`, "expected table, got 't' at offset 7"},
+		{`create table t`, "expected '(', got end of input"},
+		{`create table t (a, )`, "expected name, got ')' at offset 19"},
+		{`create table t (a default)`, "expected default value, got ')' at offset 25"},
+		{`create table t (a default -x)`, "expected number, got 'x' at offset 27"},
+		{`create table t (a check (a > (1))`, "expected ')', got end of input"},
+		{`create table t (a primary)`, "expected column constraint, got 'primary' at offset 18"},
+		{`create table t (a not null on conflict skip)`, "expected conflict resolution, got 'skip' at offset 39"},
+		{`create table t (a references p on delete nothing)`, "expected foreign key action, got 'nothing' at offset 41"},
+		{`create table t (a) x`, "unexpected trailing input, got 'x' at offset 19"},
+		{`create table t as select 1`, "'create table ... as select' is not supported, got 'as' at offset 15"},
+		{`create index i on t ()`, "expected expression, got ')' at offset 21"},
+		{`create index i t (a)`, "expected on, got 't' at offset 15"},
+	}
+	for _, tt := range tests {
+		_, err := ParseCreateTable(tt.src)
+		if tt.src[7] == 'i' {
+			_, err = ParseCreateIndex(tt.src)
+		}
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestSkipExpr(t *testing.T) {
+	tests := []struct {
+		src  string
+		stop []string
+		want string
+		rest string
+	}{
+		{`a + b, c`, nil, "a + b", ","},
+		{`f(a, (b, c)) + 1)`, nil, "f(a, (b, c)) + 1", ")"},
+		{`a; b`, nil, "a", ";"},
+		{`a collate nocase`, []string{"collate"}, "a", "collate"},
+		{`(a collate nocase) desc`, []string{"collate", "desc"}, "(a collate nocase)", "desc"},
+		{`case when a then b end desc`, []string{"desc"}, "case when a then b end", "desc"},
+		{`case x when 1 then 'a, b' end, y`, nil, "case x when 1 then 'a, b' end", ","},
+	}
+	for _, tt := range tests {
+		p, err := NewParser(tt.src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := p.SkipExpr(tt.stop...)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if got != tt.want || p.Peek().Text != tt.rest {
+			t.Errorf("%s: got %q before %q, want %q before %q", tt.src, got, p.Peek().Text, tt.want, tt.rest)
+		}
+	}
+}
+
+// dump formats nested pointers for error messages.
+func dump(v any) string {
+	b, _ := json.Marshal(v)
+	return string(b)
+}
@@ -0,0 +1,54 @@
+package sqltext
+
+import "strings"
+
+// QuoteName produces the identifier as is when it can be used bare, otherwise
+// encloses it in double quotes. Bare identifiers are limited to ASCII
+// letters, digits and underscores, and must not be keywords.
+func QuoteName(name string) string {
+	if !needs_quotes(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func needs_quotes(name string) bool {
+	if name == "" || is_digit(name[0]) {
+		return true
+	}
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c == '_' || is_digit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')) {
+			return true
+		}
+	}
+	_, kw := keywords[strings.ToLower(name)]
+	return kw
+}
+
+// keywords lists the sqlite keywords, see
+// https://www.sqlite.org/lang_keywords.html, along with true and false that
+// are parsed as literals in expressions.
+var keywords = func() map[string]struct{} {
+	m := map[string]struct{}{}
+	for _, k := range strings.Fields(`
+		abort action add after all alter always analyze and as asc attach
+		autoincrement before begin between by cascade case cast check collate
+		column commit conflict constraint create cross current current_date
+		current_time current_timestamp database default deferrable deferred
+		delete desc detach distinct do drop each else end escape except
+		exclude exclusive exists explain fail filter first following for
+		foreign from full generated glob group groups having if ignore
+		immediate in index indexed initially inner insert instead intersect
+		into is isnull join key last left like limit match materialized
+		natural no not nothing notnull null nulls of offset on or order others
+		outer over partition plan pragma preceding primary query raise range
+		recursive references regexp reindex release rename replace restrict
+		returning right rollback row rows savepoint select set table temp
+		temporary then ties to transaction trigger unbounded union unique
+		update using vacuum values view virtual when where window with
+		without true false`) {
+		m[k] = struct{}{}
+	}
+	return m
+}()
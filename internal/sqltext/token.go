@@ -0,0 +1,222 @@
+// Package sqltext contains a small tokenizer and parser for the subset of
+// SQLite DDL that is needed to recover schema details which are not reported
+// by pragmas.
+package sqltext
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Kind int
+
+const (
+	EOF    Kind = iota
+	Ident       // bare identifier or keyword
+	Quoted      // "quoted", [bracketed] or `backticked` identifier
+	String      // 'string literal'
+	Number      // numeric literal
+	Blob        // x'blob literal'
+	Param       // ?, ?NNN, :name, @name, $name
+	Punct       // operators and punctuation
+)
+
+// Token is a lexical token with its byte span in the source text.
+type Token struct {
+	Kind Kind
+	Text string
+	Pos  int
+	End  int
+}
+
+// Is returns true if the token is a bare identifier matching the keyword
+// (case-insensitive).
+func (t Token) Is(keyword string) bool {
+	return t.Kind == Ident && strings.EqualFold(t.Text, keyword)
+}
+
+// IsPunct returns true if the token is the specified punctuation.
+func (t Token) IsPunct(p string) bool {
+	return t.Kind == Punct && t.Text == p
+}
+
+// IsName returns true if the token can be used as a name.
+func (t Token) IsName() bool {
+	return t.Kind == Ident || t.Kind == Quoted || t.Kind == String
+}
+
+// Value returns the token text with identifier or string quotes removed.
+func (t Token) Value() string {
+	switch t.Kind {
+	case Quoted, String:
+		n := len(t.Text)
+		q := t.Text[0]
+		s := t.Text[1 : n-1]
+		switch q {
+		case '"':
+			return strings.ReplaceAll(s, `""`, `"`)
+		case '\'':
+			return strings.ReplaceAll(s, `''`, `'`)
+		case '`':
+			return strings.ReplaceAll(s, "``", "`")
+		default:
+			return s
+		}
+	default:
+		return t.Text
+	}
+}
+
+// Tokenize splits SQL text into tokens, skipping whitespace and comments. The
+// returned slice is always terminated with an EOF token.
+func Tokenize(src string) ([]Token, error) {
+	tt := []Token{}
+	i, n := 0, len(src)
+	for i < n {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			i++
+			continue
+
+		case c == '-' && i+1 < n && src[i+1] == '-':
+			for i < n && src[i] != '\n' {
+				i++
+			}
+			continue
+
+		case c == '/' && i+1 < n && src[i+1] == '*':
+			e := strings.Index(src[i+2:], "*/")
+			if e < 0 {
+				i = n
+			} else {
+				i += e + 4
+			}
+			continue
+
+		case c == '"' || c == '`' || c == '\'':
+			i = skip_quoted(src, i, c)
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated quote at offset %d", start)
+			}
+			k := Quoted
+			if c == '\'' {
+				k = String
+			}
+			tt = append(tt, Token{Kind: k, Text: src[start:i], Pos: start, End: i})
+
+		case c == '[':
+			e := strings.IndexByte(src[i:], ']')
+			if e < 0 {
+				return nil, fmt.Errorf("unterminated identifier at offset %d", start)
+			}
+			i += e + 1
+			tt = append(tt, Token{Kind: Quoted, Text: src[start:i], Pos: start, End: i})
+
+		case (c == 'x' || c == 'X') && i+1 < n && src[i+1] == '\'':
+			i = skip_quoted(src, i+1, '\'')
+			if i < 0 {
+				return nil, fmt.Errorf("unterminated blob literal at offset %d", start)
+			}
+			tt = append(tt, Token{Kind: Blob, Text: src[start:i], Pos: start, End: i})
+
+		case is_digit(c) || (c == '.' && i+1 < n && is_digit(src[i+1])):
+			i = skip_number(src, i)
+			tt = append(tt, Token{Kind: Number, Text: src[start:i], Pos: start, End: i})
+
+		case is_ident_start(c):
+			for i < n && is_ident_char(src[i]) {
+				i++
+			}
+			tt = append(tt, Token{Kind: Ident, Text: src[start:i], Pos: start, End: i})
+
+		case c == '?':
+			i++
+			for i < n && is_digit(src[i]) {
+				i++
+			}
+			tt = append(tt, Token{Kind: Param, Text: src[start:i], Pos: start, End: i})
+
+		case (c == ':' || c == '@' || c == '$') && i+1 < n && is_ident_char(src[i+1]):
+			i++
+			for i < n && is_ident_char(src[i]) {
+				i++
+			}
+			tt = append(tt, Token{Kind: Param, Text: src[start:i], Pos: start, End: i})
+
+		default:
+			i += punct_len(src[i:])
+			tt = append(tt, Token{Kind: Punct, Text: src[start:i], Pos: start, End: i})
+		}
+	}
+	tt = append(tt, Token{Kind: EOF, Pos: n, End: n})
+	return tt, nil
+}
+
+func skip_quoted(src string, i int, q byte) int {
+	i++
+	for i < len(src) {
+		if src[i] == q {
+			if i+1 < len(src) && src[i+1] == q {
+				i += 2
+				continue
+			}
+			return i + 1
+		}
+		i++
+	}
+	return -1
+}
+
+func skip_number(src string, i int) int {
+	n := len(src)
+	if src[i] == '0' && i+1 < n && (src[i+1] == 'x' || src[i+1] == 'X') {
+		i += 2
+		for i < n && (is_digit(src[i]) || strings.IndexByte("abcdefABCDEF", src[i]) >= 0) {
+			i++
+		}
+		return i
+	}
+	for i < n && (is_digit(src[i]) || src[i] == '_') {
+		i++
+	}
+	if i < n && src[i] == '.' {
+		i++
+		for i < n && (is_digit(src[i]) || src[i] == '_') {
+			i++
+		}
+	}
+	if i < n && (src[i] == 'e' || src[i] == 'E') {
+		j := i + 1
+		if j < n && (src[j] == '+' || src[j] == '-') {
+			j++
+		}
+		if j < n && is_digit(src[j]) {
+			i = j
+			for i < n && is_digit(src[i]) {
+				i++
+			}
+		}
+	}
+	return i
+}
+
+func punct_len(s string) int {
+	for _, p := range []string{"||", "<<", ">>", "<=", ">=", "==", "!=", "<>", "->>", "->"} {
+		if strings.HasPrefix(s, p) {
+			return len(p)
+		}
+	}
+	return 1
+}
+
+func is_digit(c byte) bool { return c >= '0' && c <= '9' }
+
+func is_ident_start(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func is_ident_char(c byte) bool {
+	return is_ident_start(c) || is_digit(c) || c == '$'
+}
@@ -0,0 +1,80 @@
+package sqltext
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	type tok struct {
+		Kind  Kind
+		Value string
+	}
+	tests := []struct {
+		src  string
+		want []tok
+	}{
+		{`create table t`, []tok{{Ident, "create"}, {Ident, "table"}, {Ident, "t"}}},
+		{`"a ""b"""`, []tok{{Quoted, `a "b"`}}},
+		{`[a "b"]`, []tok{{Quoted, `a "b"`}}},
+		{"`a``b`", []tok{{Quoted, "a`b"}}},
+		{`'it''s'`, []tok{{String, "it's"}}},
+		{`''`, []tok{{String, ""}}},
+		{"a -- comment ; 'x'\nb", []tok{{Ident, "a"}, {Ident, "b"}}},
+		{"a /* comment\n ; 'x' */ b", []tok{{Ident, "a"}, {Ident, "b"}}},
+		{"a /* unterminated", []tok{{Ident, "a"}}},
+		{"a --", []tok{{Ident, "a"}}},
+		{`f((a), (b, (c)))`, []tok{{Ident, "f"}, {Punct, "("}, {Punct, "("}, {Ident, "a"}, {Punct, ")"},
+			{Punct, ","}, {Punct, "("}, {Ident, "b"}, {Punct, ","}, {Punct, "("}, {Ident, "c"},
+			{Punct, ")"}, {Punct, ")"}, {Punct, ")"}}},
+		{`x'0aFF' X''`, []tok{{Blob, "x'0aFF'"}, {Blob, "X''"}}},
+		{`x 'a'`, []tok{{Ident, "x"}, {String, "a"}}},
+		{`1 1.5 .5 1e10 1.5E-3 0x1F 1_000`, []tok{{Number, "1"}, {Number, "1.5"}, {Number, ".5"},
+			{Number, "1e10"}, {Number, "1.5E-3"}, {Number, "0x1F"}, {Number, "1_000"}}},
+		{`1e`, []tok{{Number, "1"}, {Ident, "e"}}},
+		{`-1`, []tok{{Punct, "-"}, {Number, "1"}}},
+		{`? ?12 :a @b $c`, []tok{{Param, "?"}, {Param, "?12"}, {Param, ":a"}, {Param, "@b"}, {Param, "$c"}}},
+		{`a||b<>c->>d->e<=f`, []tok{{Ident, "a"}, {Punct, "||"}, {Ident, "b"}, {Punct, "<>"}, {Ident, "c"},
+			{Punct, "->>"}, {Ident, "d"}, {Punct, "->"}, {Ident, "e"}, {Punct, "<="}, {Ident, "f"}}},
+		{`ключ`, []tok{{Ident, "ключ"}}},
+	}
+	for _, tt := range tests {
+		tokens, err := Tokenize(tt.src)
+		if err != nil {
+			t.Errorf("%s: %v", tt.src, err)
+			continue
+		}
+		if last := tokens[len(tokens)-1]; last.Kind != EOF || last.Pos != len(tt.src) {
+			t.Errorf("%s: not terminated with EOF: %+v", tt.src, last)
+		}
+		got := []tok{}
+		for _, k := range tokens[:len(tokens)-1] {
+			if tt.src[k.Pos:k.End] != k.Text {
+				t.Errorf("%s: span %d:%d does not match %q", tt.src, k.Pos, k.End, k.Text)
+			}
+			got = append(got, tok{k.Kind, k.Value()})
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s:\n got %v\nwant %v", tt.src, got, tt.want)
+		}
+	}
+}
+
+func TestTokenizeErrors(t *testing.T) {
+	tests := []struct {
+		src  string
+		want string
+	}{
+		{`a "b`, "unterminated quote at offset 2"},
+		{`'it''s`, "unterminated quote at offset 0"},
+		{"a `b", "unterminated quote at offset 2"},
+		{`a [b`, "unterminated identifier at offset 2"},
+		{`a, x'0a`, "unterminated blob literal at offset 3"},
+	}
+	for _, tt := range tests {
+		_, err := Tokenize(tt.src)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("%s: got %v, want %s", tt.src, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package sqltext
+
+import "strings"
+
+// CreateTrigger is the parsed form of a 'create trigger' statement.
+type CreateTrigger struct {
+	Temporary   bool
+	IfNotExists bool
+	Schema      string
+	Name        string
+	Timing      string   // "before", "after", "instead of" or empty
+	Event       string   // "delete", "insert" or "update"
+	Columns     []string // 'update of' columns
+	Table       string
+	ForEachRow  bool
+	When        string
+	Body        string // statements between 'begin' and 'end'
+}
+
+// ParseCreateTrigger parses a 'create trigger' statement.
+func ParseCreateTrigger(src string) (*CreateTrigger, error) {
+	p, err := NewParser(src)
+	if err != nil {
+		return nil, err
+	}
+	ct, err := p.CreateTrigger()
+	if err != nil {
+		return nil, err
+	}
+	if !p.AtEnd() {
+		return nil, p.Errorf("unexpected trailing input")
+	}
+	return ct, nil
+}
+
+// CreateTrigger parses a 'create trigger' statement.
+func (p *Parser) CreateTrigger() (*CreateTrigger, error) {
+	ct := &CreateTrigger{}
+	if err := p.Expect("create"); err != nil {
+		return nil, err
+	}
+	ct.Temporary = p.Accept("temp") || p.Accept("temporary")
+	if err := p.Expect("trigger"); err != nil {
+		return nil, err
+	}
+	ct.IfNotExists = p.Accept("if", "not", "exists")
+	var err error
+	ct.Schema, ct.Name, err = p.QualifiedName()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.Accept("before"):
+		ct.Timing = "before"
+	case p.Accept("after"):
+		ct.Timing = "after"
+	case p.Accept("instead", "of"):
+		ct.Timing = "instead of"
+	}
+
+	switch {
+	case p.Accept("delete"):
+		ct.Event = "delete"
+	case p.Accept("insert"):
+		ct.Event = "insert"
+	case p.Accept("update"):
+		ct.Event = "update"
+		if p.Accept("of") {
+			for {
+				n, err := p.Name()
+				if err != nil {
+					return nil, err
+				}
+				ct.Columns = append(ct.Columns, n)
+				if !p.AcceptPunct(",") {
+					break
+				}
+			}
+		}
+	default:
+		return nil, p.Errorf("expected delete, insert or update")
+	}
+
+	if err := p.Expect("on"); err != nil {
+		return nil, err
+	}
+	if ct.Table, err = p.Name(); err != nil {
+		return nil, err
+	}
+	ct.ForEachRow = p.Accept("for", "each", "row")
+	if p.Accept("when") {
+		if ct.When, err = p.SkipExpr("begin"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Expect("begin"); err != nil {
+		return nil, err
+	}
+
+	// the body ends with 'end' that does not close a case expression
+	start := p.pos
+	case_depth := 0
+	for {
+		t := p.Peek()
+		switch {
+		case t.Kind == EOF:
+			return nil, p.Errorf("expected end")
+		case t.Is("case"):
+			case_depth++
+		case t.Is("end") && case_depth > 0:
+			case_depth--
+		case t.Is("end"):
+			ct.Body = strings.TrimSpace(p.Text(start, p.pos))
+			p.pos++
+			return ct, nil
+		}
+		p.pos++
+	}
+}
@@ -0,0 +1,41 @@
+package sqltext
+
+// CreateView is the parsed form of a 'create view' statement.
+type CreateView struct {
+	Temporary   bool
+	IfNotExists bool
+	Schema      string
+	Name        string
+	Columns     []string
+	Select      string
+}
+
+// CreateView parses a 'create view' statement.
+func (p *Parser) CreateView() (*CreateView, error) {
+	cv := &CreateView{}
+	if err := p.Expect("create"); err != nil {
+		return nil, err
+	}
+	cv.Temporary = p.Accept("temp") || p.Accept("temporary")
+	if err := p.Expect("view"); err != nil {
+		return nil, err
+	}
+	cv.IfNotExists = p.Accept("if", "not", "exists")
+	var err error
+	cv.Schema, cv.Name, err = p.QualifiedName()
+	if err != nil {
+		return nil, err
+	}
+	if p.Peek().IsPunct("(") {
+		if cv.Columns, err = p.name_list(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.Expect("as"); err != nil {
+		return nil, err
+	}
+	if cv.Select = p.SkipStatement(); cv.Select == "" {
+		return nil, p.Errorf("expected select")
+	}
+	return cv, nil
+}
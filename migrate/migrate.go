@@ -0,0 +1,258 @@
+// Package migrate applies an ordered list of schema migrations to a sqlite
+// database.
+//
+// The applied version is tracked in 'pragma user_version', each applied step
+// is also recorded in the history table along with its checksum. Migrations
+// refuse to run if an already applied step has been modified.
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// HistoryTable is the name of the table where applied migrations are recorded.
+const HistoryTable = "schema_migrations"
+
+// Migration is a single versioned step. Versions are assigned by position: the
+// first migration in the list brings the database to version 1.
+type Migration struct {
+	Name string
+	SQL  string
+	Func func(tx *Tx) error
+}
+
+// Tx is the transaction a migration runs in. It is started with 'begin
+// immediate' on a dedicated connection, which database/sql transactions do not
+// support without changing the connection string.
+type Tx struct {
+	conn *sql.Conn
+}
+
+// Exec executes a statement within the transaction.
+func (tx *Tx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return tx.conn.ExecContext(context.Background(), query, args...)
+}
+
+// ExecContext executes a statement within the transaction.
+func (tx *Tx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tx.conn.ExecContext(ctx, query, args...)
+}
+
+// Query executes a query within the transaction.
+func (tx *Tx) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.conn.QueryContext(context.Background(), query, args...)
+}
+
+// QueryContext executes a query within the transaction.
+func (tx *Tx) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return tx.conn.QueryContext(ctx, query, args...)
+}
+
+// QueryRow executes a query that returns at most one row within the
+// transaction.
+func (tx *Tx) QueryRow(query string, args ...interface{}) *sql.Row {
+	return tx.conn.QueryRowContext(context.Background(), query, args...)
+}
+
+// QueryRowContext executes a query that returns at most one row within the
+// transaction.
+func (tx *Tx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tx.conn.QueryRowContext(ctx, query, args...)
+}
+
+// SQL produces a migration that executes the sql script.
+func SQL(name string, script string) Migration {
+	return Migration{Name: name, SQL: script}
+}
+
+// Func produces a migration that runs a callback. Such migrations are
+// identified by their name only, changes in the callback are not detected.
+func Func(name string, f func(tx *Tx) error) Migration {
+	return Migration{Name: name, Func: f}
+}
+
+// CreateSchema produces a migration that creates tables and indices from the
+// data model.
+func CreateSchema(name string, db *schema.Database) Migration {
+	b := bytes.Buffer{}
+	for _, t := range db.Tables {
+		t.CreateStatements(&b)
+	}
+	return SQL(name, b.String())
+}
+
+// Checksum returns the hex encoded SHA-256 of the migration name and sql.
+func (m *Migration) Checksum() string {
+	h := sha256.New()
+	h.Write([]byte(m.Name))
+	h.Write([]byte{0})
+	h.Write([]byte(m.SQL))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (m *Migration) run(tx *Tx) error {
+	if m.SQL != "" {
+		if _, err := tx.Exec(m.SQL); err != nil {
+			return err
+		}
+	}
+	if m.Func != nil {
+		return m.Func(tx)
+	}
+	return nil
+}
+
+// Version returns the schema version recorded in the database.
+func Version(src schema.Querier) (int, error) {
+	var v int
+	err := src.QueryRow("pragma user_version").Scan(&v)
+	return v, err
+}
+
+// Up applies pending migrations, each within its own transaction. It returns
+// the number of migrations applied.
+//
+// Before applying anything, Up verifies the checksums of already applied
+// migrations against the history table and fails with ErrChecksumMismatch if
+// any of them has been modified. Databases with versions set without the
+// history table are accepted as is.
+func Up(db *sql.DB, migrations ...Migration) (applied int, err error) {
+	_, err = db.Exec(`create table if not exists ` + HistoryTable + ` (
+    version     integer    not null primary key,
+    name        text       not null,
+    checksum    text       not null,
+    applied_at  timestamp  not null default current_timestamp
+);`)
+	if err != nil {
+		return 0, fmt.Errorf("creating migration history: %w", err)
+	}
+
+	current, err := Version(db)
+	if err != nil {
+		return 0, fmt.Errorf("querying schema version: %w", err)
+	}
+	if current < 0 || current > len(migrations) {
+		return 0, &ErrUnknownVersion{Version: current, Known: len(migrations)}
+	}
+	if err = verify(db, migrations[:current]); err != nil {
+		return 0, err
+	}
+
+	for i := current; i < len(migrations); i++ {
+		ok, err := apply(db, i+1, &migrations[i])
+		if err != nil {
+			return applied, fmt.Errorf("applying migration %d (%s): %w", i+1, migrations[i].Name, err)
+		}
+		if ok {
+			applied++
+		}
+	}
+	return applied, nil
+}
+
+// verify compares recorded checksums with the applied migrations.
+func verify(src schema.Querier, applied []Migration) error {
+	rows, err := src.Query("select version, checksum from "+HistoryTable+" where version <= ?", len(applied))
+	if err != nil {
+		return fmt.Errorf("querying migration history: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err = rows.Scan(&version, &checksum); err != nil {
+			return fmt.Errorf("querying migration history: %w", err)
+		}
+		if version < 1 {
+			continue
+		}
+		if m := &applied[version-1]; m.Checksum() != checksum {
+			return &ErrChecksumMismatch{Version: version, Name: m.Name}
+		}
+	}
+	return rows.Err()
+}
+
+// apply runs a single migration bringing the database to the specified
+// version. Migrations applied concurrently by another connection are skipped.
+func apply(db *sql.DB, version int, m *Migration) (bool, error) {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	// the write lock is taken before the version is read: concurrent runners
+	// wait for each other within the busy timeout and see the version
+	// committed by the previous one
+	if _, err = conn.ExecContext(ctx, "begin immediate"); err != nil {
+		return false, err
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			conn.ExecContext(ctx, "rollback")
+		}
+	}()
+	tx := &Tx{conn: conn}
+
+	current, err := Version(tx)
+	if err != nil {
+		return false, err
+	}
+	if current >= version {
+		return false, nil
+	}
+
+	if err = m.run(tx); err != nil {
+		return false, err
+	}
+	_, err = tx.Exec("insert or replace into "+HistoryTable+" (version, name, checksum) values (?, ?, ?)",
+		version, m.Name, m.Checksum())
+	if err != nil {
+		return false, err
+	}
+	if _, err = tx.Exec(fmt.Sprintf("pragma user_version = %d", version)); err != nil {
+		return false, err
+	}
+	if _, err = conn.ExecContext(ctx, "commit"); err != nil {
+		return false, err
+	}
+	committed = true
+	return true, nil
+}
+
+// ErrChecksumMismatch is returned when an already applied migration has been
+// modified.
+type ErrChecksumMismatch struct {
+	Version int
+	Name    string
+}
+
+// ErrUnknownVersion is returned when the database has more migrations applied
+// than the list contains, or a negative version.
+type ErrUnknownVersion struct {
+	Version int
+	Known   int
+}
+
+// Error implements support for the standard error interface.
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("checksum mismatch in applied migration %d (%s)", e.Version, e.Name)
+}
+
+// Error implements support for the standard error interface.
+func (e *ErrUnknownVersion) Error() string {
+	if e.Version < 0 {
+		return fmt.Sprintf("database schema version %d is invalid", e.Version)
+	}
+	return fmt.Sprintf("database schema version %d is newer than the latest known version %d", e.Version, e.Known)
+}
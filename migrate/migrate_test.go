@@ -0,0 +1,116 @@
+package migrate
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/adnsv/go-db3/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func ExampleUp() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+
+	migrations := []Migration{
+		CreateSchema("initial", &schema.Database{Tables: []*schema.Table{{
+			Name: "users",
+			Columns: []*schema.Column{
+				{Name: "id", Type: schema.Int64},
+				{Name: "name", Type: schema.Text},
+			},
+			PK: []string{"id"},
+		}}}),
+		SQL("add email", "alter table users add column email text;"),
+	}
+
+	n, err := Up(db, migrations...)
+	fmt.Println(n, err)
+
+	migrations = append(migrations, Func("seed", func(tx *Tx) error {
+		_, err := tx.Exec("insert into users (id, name) values (1, 'admin')")
+		return err
+	}))
+	n, err = Up(db, migrations...)
+	fmt.Println(n, err)
+
+	v, _ := Version(db)
+	fmt.Println("version", v)
+
+	migrations[1].SQL = "alter table users add column mail text;"
+	_, err = Up(db, migrations...)
+	fmt.Println(err)
+
+	_, err = Up(db, migrations[:1]...)
+	fmt.Println(err)
+
+	db.Exec("pragma user_version = -1")
+	_, err = Up(db, migrations...)
+	fmt.Println(err)
+
+	// Output:
+	// 2 <nil>
+	// 1 <nil>
+	// version 3
+	// checksum mismatch in applied migration 2 (add email)
+	// database schema version 3 is newer than the latest known version 1
+	// database schema version -1 is invalid
+}
+
+func TestUpConcurrent(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+
+	migrations := []Migration{
+		SQL("initial", "create table counter (n int)"),
+	}
+	for i := 0; i < 5; i++ {
+		migrations = append(migrations, Func(fmt.Sprintf("step %d", i), func(tx *Tx) error {
+			time.Sleep(10 * time.Millisecond)
+			_, err := tx.Exec("insert into counter values (1)")
+			return err
+		}))
+	}
+
+	const runners = 4
+	wg := sync.WaitGroup{}
+	applied := make([]int, runners)
+	errs := make([]error, runners)
+	for r := 0; r < runners; r++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			db, err := sql.Open("sqlite3", path)
+			if err != nil {
+				errs[r] = err
+				return
+			}
+			defer db.Close()
+			applied[r], errs[r] = Up(db, migrations...)
+		}()
+	}
+	wg.Wait()
+
+	total := 0
+	for r := 0; r < runners; r++ {
+		if errs[r] != nil {
+			t.Fatalf("runner %d: %v", r, errs[r])
+		}
+		total += applied[r]
+	}
+	if total != len(migrations) {
+		t.Fatalf("applied %d migrations, want %d", total, len(migrations))
+	}
+
+	db, _ := sql.Open("sqlite3", path)
+	defer db.Close()
+	var n int
+	db.QueryRow("select count(*) from counter").Scan(&n)
+	if n != len(migrations)-1 {
+		t.Fatalf("counter has %d rows, want %d", n, len(migrations)-1)
+	}
+}
@@ -0,0 +1,158 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Preparer is a generic statement preparer, typically should be hooked to
+// sql.Tx or sql.DB.
+type Preparer interface {
+	Prepare(query string) (*sql.Stmt, error)
+}
+
+// DefaultMaxVariables is the default value of SQLITE_MAX_VARIABLE_NUMBER in
+// sqlite 3.32.0 and newer.
+const DefaultMaxVariables = 32766
+
+// InsertManyOptions control the batching in InsertMany.
+type InsertManyOptions struct {
+	// MaxVariables limits the number of parameters in a single statement,
+	// zero means DefaultMaxVariables. Use 999 for sqlite versions prior to
+	// 3.32.0.
+	MaxVariables int
+
+	// Transaction wraps all the inserts into a single transaction. It is
+	// ignored when dst does not support Begin, e.g. when it is a transaction
+	// already.
+	Transaction bool
+}
+
+// InsertMany adds rows to the table using multi-row insert statements. Rows
+// are split into chunks that fit within the limit on the number of statement
+// parameters, prepared statements are reused for chunks of the same size.
+//
+// Rows with zero rowid alias keys are inserted one at a time, their fields
+// receive the generated keys as in Insert.
+//
+// Nil opts are equivalent to zero InsertManyOptions.
+func InsertMany[T any](dst Preparer, table *Table, rows []*T, opts *InsertManyOptions) (int64, error) {
+	return InsertManyContext(context.Background(), preparer_with_context(dst), table, rows, opts)
+}
+
+// InsertManyContext is the context-aware version of InsertMany. With
+// Transaction option, dst needs to support BeginTx.
+func InsertManyContext[T any](ctx context.Context, dst PreparerContext, table *Table, rows []*T, opts *InsertManyOptions) (inserted int64, err error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+	if opts == nil {
+		opts = &InsertManyOptions{}
+	}
+	max_vars := opts.MaxVariables
+	if max_vars <= 0 {
+		max_vars = DefaultMaxVariables
+	}
+
+	bb, err := table.bind_writable(rows[0])
+	if err != nil {
+		return 0, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	selectors := bb.selectors
+	chunk := max_vars / len(selectors)
+	if chunk < 1 {
+		return 0, fmt.Errorf("binding table %s: %d columns exceed the limit of %d variables",
+			table.Name, len(selectors), max_vars)
+	}
+
+	type beginner interface {
+		BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+	}
+	if b, ok := dst.(beginner); ok && opts.Transaction {
+		tx, err := b.BeginTx(ctx, nil)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err != nil {
+				tx.Rollback()
+			} else {
+				err = tx.Commit()
+			}
+		}()
+		dst = tx
+	}
+
+	// statements are keyed by the number of rows, zero stands for a single
+	// row without the key
+	stmts := map[int]*sql.Stmt{}
+	defer func() {
+		for _, s := range stmts {
+			s.Close()
+		}
+	}()
+	prepare := func(n int, selectors []string) (*sql.Stmt, error) {
+		if stmt, ok := stmts[n]; ok {
+			return stmt, nil
+		}
+		stmt, err := dst.PrepareContext(ctx, insert_sql(table.Name, selectors, max(n, 1)))
+		if err != nil {
+			return nil, err
+		}
+		stmts[n] = stmt
+		return stmt, nil
+	}
+
+	args := make([]any, 0, chunk*len(selectors))
+	for start := 0; start < len(rows); {
+		bb, err := table.bind_writable(rows[start])
+		if err != nil {
+			return inserted, fmt.Errorf("binding table %s: %w", table.Name, err)
+		}
+		if key, keyless := bb.without_zero_key(table.rowid); key.IsValid() {
+			stmt, err := prepare(0, keyless.selectors)
+			if err != nil {
+				return inserted, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+			}
+			r, err := stmt.ExecContext(ctx, keyless.values()...)
+			if err != nil {
+				return inserted, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+			}
+			id, err := r.LastInsertId()
+			if err != nil {
+				return inserted, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+			}
+			set_key(key, id)
+			inserted++
+			start++
+			continue
+		}
+
+		// the chunk ends before the next row with a zero key
+		args = append(args[:0], bb.values()...)
+		end := start + 1
+		for ; end < len(rows) && end-start < chunk; end++ {
+			bb, err := table.bind_writable(rows[end])
+			if err != nil {
+				return inserted, fmt.Errorf("binding table %s: %w", table.Name, err)
+			}
+			if key, _ := bb.without_zero_key(table.rowid); key.IsValid() {
+				break
+			}
+			args = append(args, bb.values()...)
+		}
+
+		n := end - start
+		stmt, err := prepare(n, selectors)
+		if err != nil {
+			return inserted, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+		}
+		if _, err = stmt.ExecContext(ctx, args...); err != nil {
+			return inserted, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+		}
+		inserted += int64(n)
+		start = end
+	}
+	return inserted, nil
+}
@@ -0,0 +1,67 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExampleInsertMany() {
+
+	type Point struct {
+		X int `orm:"x"`
+		Y int `orm:"y"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table points (x int, y int)`)
+
+	points, _ := GetTable(db, "points")
+
+	rows := []*Point{}
+	for i := 0; i < 10; i++ {
+		rows = append(rows, &Point{X: i, Y: i * i})
+	}
+
+	// 6 variables fit 3 rows per statement
+	n, err := InsertMany(db, points, rows, &InsertManyOptions{MaxVariables: 6, Transaction: true})
+	fmt.Println(n, err)
+
+	var count, sum int
+	db.QueryRow("select count(*), sum(y) from points").Scan(&count, &sum)
+	fmt.Println(count, sum)
+
+	_, err = InsertMany(db, points, rows, &InsertManyOptions{MaxVariables: 1})
+	fmt.Println(err)
+
+	// Output:
+	// 10 <nil>
+	// 10 285
+	// binding table points: 2 columns exceed the limit of 1 variables
+}
+
+func ExampleInsertMany_rowid() {
+
+	type Item struct {
+		ID   int64  `orm:"id"`
+		Name string `orm:"name"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table items (id integer primary key, name text)`)
+
+	items, _ := GetTable(db, "items")
+
+	rows := []*Item{{ID: 10, Name: "a"}, {ID: 11, Name: "b"}, {Name: "c"}, {ID: 20, Name: "d"}, {Name: "e"}}
+	n, err := InsertMany(db, items, rows, &InsertManyOptions{Transaction: true})
+	fmt.Println(n, err)
+	for _, r := range rows {
+		fmt.Print(r.ID, r.Name, " ")
+	}
+	fmt.Println()
+
+	// Output:
+	// 5 <nil>
+	// 10a 11b 12c 20d 21e
+}
@@ -0,0 +1,195 @@
+package orm
+
+import (
+	"bytes"
+	"reflect"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Expr is a Condition that represents a boolean expression. Expressions can be
+// combined with And, Or and Not.
+type Expr interface {
+	Condition
+
+	// Expr returns the expression sql without the 'where' keyword.
+	Expr() (sql string, args []any)
+
+	// Columns returns the names of the columns referenced by the expression.
+	Columns() []string
+}
+
+// predicate is a single-column expression
+type predicate struct {
+	column string
+	sql    string // column is prepended
+	args   []any
+}
+
+func (p *predicate) Sql() (sql string, args []any) { return where_sql(p) }
+func (p *predicate) Expr() (sql string, args []any) {
+	return schema.QuoteName(p.column) + p.sql, p.args
+}
+func (p *predicate) Columns() []string { return []string{p.column} }
+
+// Eq produces 'column = ?' expression, nil value produces 'column is null'.
+func Eq(column string, v any) Expr {
+	if v == nil {
+		return IsNull(column)
+	}
+	return &predicate{column: column, sql: " = ?", args: []any{v}}
+}
+
+// Ne produces 'column <> ?' expression, nil value produces 'column is not
+// null'.
+func Ne(column string, v any) Expr {
+	if v == nil {
+		return IsNotNull(column)
+	}
+	return &predicate{column: column, sql: " <> ?", args: []any{v}}
+}
+
+// Lt produces 'column < ?' expression.
+func Lt(column string, v any) Expr {
+	return &predicate{column: column, sql: " < ?", args: []any{v}}
+}
+
+// Le produces 'column <= ?' expression.
+func Le(column string, v any) Expr {
+	return &predicate{column: column, sql: " <= ?", args: []any{v}}
+}
+
+// Gt produces 'column > ?' expression.
+func Gt(column string, v any) Expr {
+	return &predicate{column: column, sql: " > ?", args: []any{v}}
+}
+
+// Ge produces 'column >= ?' expression.
+func Ge(column string, v any) Expr {
+	return &predicate{column: column, sql: " >= ?", args: []any{v}}
+}
+
+// Like produces 'column like ?' expression.
+func Like(column string, pattern string) Expr {
+	return &predicate{column: column, sql: " like ?", args: []any{pattern}}
+}
+
+// Between produces 'column between ? and ?' expression.
+func Between(column string, lo, hi any) Expr {
+	return &predicate{column: column, sql: " between ? and ?", args: []any{lo, hi}}
+}
+
+// IsNull produces 'column is null' expression.
+func IsNull(column string) Expr {
+	return &predicate{column: column, sql: " is null"}
+}
+
+// IsNotNull produces 'column is not null' expression.
+func IsNotNull(column string) Expr {
+	return &predicate{column: column, sql: " is not null"}
+}
+
+// In produces 'column in (?, ?, ...)' expression with a placeholder for each
+// value. A single slice argument (other than []byte) is expanded into its
+// elements.
+func In(column string, values ...any) Expr {
+	if len(values) == 1 {
+		if v := reflect.ValueOf(values[0]); v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+			values = make([]any, v.Len())
+			for i := range values {
+				values[i] = v.Index(i).Interface()
+			}
+		}
+	}
+	b := bytes.Buffer{}
+	b.WriteString(" in (")
+	write_placeholders(&b, len(values))
+	b.WriteByte(')')
+	return &predicate{column: column, sql: b.String(), args: values}
+}
+
+// junction combines expressions with 'and' or 'or'
+type junction struct {
+	op    string
+	empty string
+	items []Expr
+}
+
+func (j *junction) Sql() (sql string, args []any) { return where_sql(j) }
+
+func (j *junction) Expr() (sql string, args []any) {
+	if len(j.items) == 0 {
+		return j.empty, nil
+	}
+	if len(j.items) == 1 {
+		return j.items[0].Expr()
+	}
+	b := bytes.Buffer{}
+	for i, e := range j.items {
+		if i > 0 {
+			b.WriteString(j.op)
+		}
+		s, a := e.Expr()
+		b.WriteString(parenthesized(e, s))
+		args = append(args, a...)
+	}
+	return b.String(), args
+}
+
+func (j *junction) Columns() (cc []string) {
+	for _, e := range j.items {
+		cc = append(cc, e.Columns()...)
+	}
+	return
+}
+
+// And combines expressions with 'and'. Without arguments it produces an
+// expression that is always true.
+func And(exprs ...Expr) Expr {
+	return &junction{op: " and ", empty: "1", items: exprs}
+}
+
+// Or combines expressions with 'or'. Without arguments it produces an
+// expression that is always false.
+func Or(exprs ...Expr) Expr {
+	return &junction{op: " or ", empty: "0", items: exprs}
+}
+
+type negation struct {
+	e Expr
+}
+
+func (n *negation) Sql() (sql string, args []any) { return where_sql(n) }
+func (n *negation) Columns() []string             { return n.e.Columns() }
+
+func (n *negation) Expr() (sql string, args []any) {
+	s, args := n.e.Expr()
+	return "not (" + s + ")", args
+}
+
+// Not negates the expression.
+func Not(e Expr) Expr {
+	return &negation{e: e}
+}
+
+func where_sql(e Expr) (string, []any) {
+	s, args := e.Expr()
+	return "where " + s, args
+}
+
+// parenthesized wraps the expression sql in parentheses unless it is known to
+// bind tighter than 'and' and 'or'.
+func parenthesized(e Expr, s string) string {
+	switch x := e.(type) {
+	case *predicate, *negation:
+		return s
+	case *junction:
+		switch len(x.items) {
+		case 0:
+			return s
+		case 1:
+			return parenthesized(x.items[0], s)
+		}
+	}
+	return "(" + s + ")"
+}
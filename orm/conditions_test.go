@@ -0,0 +1,40 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExampleAnd() {
+
+	opts := Enumerate(
+		Eq("kind", "user"),
+		Or(In("role", []string{"admin", "owner"}), And(Not(IsNull("deleted_at")), Lt("age", 18))),
+		And(Or(Like("name", "a%"), Between("age", 20, 30))),
+		Where("length(name) > ?", 3),
+	)
+	fmt.Println(opts.Sql("t", []string{"id"}))
+	fmt.Println(opts.Args())
+
+	type Row struct {
+		ID int `orm:"id"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table t (id int, kind text, name text); insert into t values (1, 'a', 'x'), (2, 'b', 'y')`)
+	t, _ := GetTable(db, "t")
+
+	err := Select(db, t, opts, func(r *Row) error { return nil })
+	fmt.Println(err)
+
+	Select(db, t, Enumerate(Or(Eq("kind", "b"), In("id"))), func(r *Row) error {
+		fmt.Println(r.ID)
+		return nil
+	})
+
+	// Output:
+	// select id from t where kind = ? and (role in (?, ?) or (not (deleted_at is null) and age < ?)) and (name like ? or age between ? and ?) and (length(name) > ?)
+	// [user admin owner 18 a% 20 30 3]
+	// querying table t: missing columns: role, deleted_at, age
+	// 2
+}
@@ -0,0 +1,65 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+)
+
+// QuerierContext is a context-aware db query runner, typically should be
+// hooked to sql.Conn, sql.Tx or sql.DB.
+type QuerierContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// ExecerContext is a context-aware db statement runner, typically should be
+// hooked to sql.Conn, sql.Tx or sql.DB.
+type ExecerContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// PreparerContext is a context-aware statement preparer, typically should be
+// hooked to sql.Conn, sql.Tx or sql.DB.
+type PreparerContext interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// The adapters below allow non-context functions to delegate to their
+// context-aware versions. Sources that already support contexts are passed
+// through unchanged.
+
+func querier_with_context(src Querier) QuerierContext {
+	if c, ok := src.(QuerierContext); ok {
+		return c
+	}
+	return &querier_adapter{src}
+}
+
+func execer_with_context(dst Execer) ExecerContext {
+	if c, ok := dst.(ExecerContext); ok {
+		return c
+	}
+	return &execer_adapter{dst}
+}
+
+func preparer_with_context(dst Preparer) PreparerContext {
+	if c, ok := dst.(PreparerContext); ok {
+		return c
+	}
+	return &preparer_adapter{dst}
+}
+
+type querier_adapter struct{ src Querier }
+type execer_adapter struct{ dst Execer }
+type preparer_adapter struct{ dst Preparer }
+
+func (a *querier_adapter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return a.src.Query(query, args...)
+}
+
+func (a *execer_adapter) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return a.dst.Exec(query, args...)
+}
+
+func (a *preparer_adapter) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return a.dst.Prepare(query)
+}
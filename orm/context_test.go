@@ -0,0 +1,41 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func ExampleSelectContext() {
+
+	type Row struct {
+		N int `orm:"n"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table t (n int); insert into t values (1), (2), (3);`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t, _ := GetTableContext(ctx, db, "t")
+	err := SelectContext(ctx, db, t, Enumerate(), func(r *Row) error {
+		fmt.Println(r.N)
+		if r.N == 2 {
+			cancel()
+		}
+		return nil
+	})
+	fmt.Println(errors.Is(err, context.Canceled))
+
+	_, err = GetTableContext(ctx, db, "t")
+	fmt.Println(err)
+
+	// Output:
+	// 1
+	// 2
+	// true
+	// context canceled
+}
@@ -0,0 +1,341 @@
+package orm
+
+import (
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Converter translates values of type T between struct fields and table
+// columns. Converters are applied on both read and write paths.
+type Converter[T any] struct {
+	// ToColumn produces the statement argument for the field value. The
+	// result must be one of nil, int64, float64, bool, []byte, string or
+	// time.Time.
+	ToColumn func(v T) (any, error)
+
+	// FromColumn assigns the scanned column value to the field. The src is
+	// one of nil, int64, float64, bool, []byte, string or time.Time.
+	FromColumn func(src any, dst *T) error
+}
+
+// RegisterConverter sets the converter for fields of type T. Pointer fields
+// to T are converted as well, with nil pointers mapped to null values.
+func RegisterConverter[T any](c Converter[T]) {
+	register_converter(converter_key{field_t: reflect.TypeFor[T]()}, &c)
+}
+
+// RegisterColumnConverter sets the converter for fields of type T bound to
+// columns of the specified type (see schema.NormalizeType). It takes
+// precedence over the converter registered with RegisterConverter.
+func RegisterColumnConverter[T any](column_type schema.ColumnType, c Converter[T]) {
+	register_converter(converter_key{field_t: reflect.TypeFor[T](), column_type: column_type}, &c)
+}
+
+// field_converter is the type-erased form of Converter[T].
+type field_converter interface {
+	to_column(field reflect.Value) (any, error)
+	from_column(src any, field reflect.Value) error
+}
+
+func (c *Converter[T]) to_column(field reflect.Value) (any, error) {
+	return c.ToColumn(field.Interface().(T))
+}
+
+func (c *Converter[T]) from_column(src any, field reflect.Value) error {
+	return c.FromColumn(src, field.Addr().Interface().(*T))
+}
+
+type converter_key struct {
+	field_t     reflect.Type
+	column_type schema.ColumnType
+}
+
+var converters_mutex sync.RWMutex
+var converters = map[converter_key]field_converter{}
+
+// converters_version is a part of the binding cache key, it is incremented on
+// each registration, so that bindings planned with replaced converters are
+// not reused.
+var converters_version atomic.Uint64
+
+func register_converter(key converter_key, c field_converter) {
+	converters_mutex.Lock()
+	converters[key] = c
+	converters_mutex.Unlock()
+
+	converters_version.Add(1)
+	binding_cache.Clear()
+}
+
+// find_converter returns the converter for a field of type field_t bound to a
+// column of the specified type, or nil if the field is scanned directly.
+func find_converter(field_t reflect.Type, column_type schema.ColumnType, as_json bool) field_converter {
+	if as_json {
+		return json_converter{}
+	}
+	converters_mutex.RLock()
+	defer converters_mutex.RUnlock()
+	if c, ok := converters[converter_key{field_t, column_type}]; ok {
+		return c
+	}
+	if c, ok := converters[converter_key{field_t: field_t}]; ok {
+		return c
+	}
+	if field_t.Kind() == reflect.Pointer {
+		if c, ok := converters[converter_key{field_t.Elem(), column_type}]; ok {
+			return nullable_converter{c}
+		}
+		if c, ok := converters[converter_key{field_t: field_t.Elem()}]; ok {
+			return nullable_converter{c}
+		}
+	}
+	return nil
+}
+
+// nullable_converter maps nil pointers to null values.
+type nullable_converter struct{ field_converter }
+
+func (c nullable_converter) to_column(field reflect.Value) (any, error) {
+	if field.IsNil() {
+		return nil, nil
+	}
+	return c.field_converter.to_column(field.Elem())
+}
+
+func (c nullable_converter) from_column(src any, field reflect.Value) error {
+	if src == nil {
+		field.SetZero()
+		return nil
+	}
+	v := reflect.New(field.Type().Elem())
+	if err := c.field_converter.from_column(src, v.Elem()); err != nil {
+		return err
+	}
+	field.Set(v)
+	return nil
+}
+
+// json_converter stores fields tagged with the json option as JSON text, null
+// values produce zero fields.
+type json_converter struct{}
+
+func (json_converter) to_column(field reflect.Value) (any, error) {
+	b, err := json.Marshal(field.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (json_converter) from_column(src any, field reflect.Value) error {
+	field.SetZero()
+	switch x := src.(type) {
+	case nil:
+		return nil
+	case string:
+		return json.Unmarshal([]byte(x), field.Addr().Interface())
+	case []byte:
+		return json.Unmarshal(x, field.Addr().Interface())
+	default:
+		return fmt.Errorf("can not decode json from %T", src)
+	}
+}
+
+// converting is a receiver for a field with a converter, it is used both as
+// a scan destination and as a statement argument.
+type converting struct {
+	conv  field_converter
+	field reflect.Value
+}
+
+func (r *converting) Scan(src any) error {
+	if err := r.conv.from_column(src, r.field); err != nil {
+		return fmt.Errorf("converting %s: %w", r.field.Type(), err)
+	}
+	return nil
+}
+
+func (r *converting) Value() (driver.Value, error) {
+	v, err := r.conv.to_column(r.field)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s: %w", r.field.Type(), err)
+	}
+	return v, nil
+}
+
+// Layouts used by the built-in time converters.
+const (
+	TimestampLayout = "2006-01-02 15:04:05.999999999-07:00"
+	DateLayout      = "2006-01-02"
+	TimeLayout      = "15:04:05.999999999"
+)
+
+// time_layouts are accepted when reading time values from text.
+var time_layouts = []string{
+	TimestampLayout,
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	DateLayout,
+	TimeLayout,
+	"15:04",
+}
+
+func time_converter(layout string) Converter[time.Time] {
+	return Converter[time.Time]{
+		ToColumn: func(v time.Time) (any, error) {
+			return v.Format(layout), nil
+		},
+		FromColumn: func(src any, dst *time.Time) error {
+			switch x := src.(type) {
+			case nil:
+				*dst = time.Time{}
+			case time.Time:
+				*dst = x
+			case int64:
+				*dst = time.Unix(x, 0).UTC()
+			case []byte:
+				return parse_time(string(x), dst)
+			case string:
+				return parse_time(x, dst)
+			default:
+				return fmt.Errorf("can not read time from %T", src)
+			}
+			return nil
+		},
+	}
+}
+
+func parse_time(s string, dst *time.Time) error {
+	for _, layout := range time_layouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			*dst = t
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid time value %q", s)
+}
+
+func format_uuid(u [16]byte) string {
+	b := make([]byte, 36)
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b)
+}
+
+func parse_uuid(src any, dst *[16]byte) error {
+	switch x := src.(type) {
+	case nil:
+		*dst = [16]byte{}
+		return nil
+	case []byte:
+		if len(x) == 16 {
+			copy(dst[:], x)
+			return nil
+		}
+		return parse_uuid(string(x), dst)
+	case string:
+		s := x
+		if len(s) == 38 && s[0] == '{' && s[37] == '}' {
+			s = s[1:37]
+		}
+		if len(s) == 36 && s[8] == '-' && s[13] == '-' && s[18] == '-' && s[23] == '-' {
+			s = s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:]
+		}
+		if len(s) == 32 {
+			if _, err := hex.Decode(dst[:], []byte(s)); err == nil {
+				return nil
+			}
+		}
+		return fmt.Errorf("invalid uuid value %q", x)
+	default:
+		return fmt.Errorf("can not read uuid from %T", src)
+	}
+}
+
+func init() {
+	RegisterConverter(time_converter(TimestampLayout))
+	RegisterColumnConverter(schema.Timestamp, time_converter(TimestampLayout))
+	RegisterColumnConverter(schema.Date, time_converter(DateLayout))
+	RegisterColumnConverter(schema.Time, time_converter(TimeLayout))
+
+	// uuids are stored as blobs in blob columns and as text in uuid columns,
+	// other [16]byte fields are left to the driver
+	RegisterColumnConverter(schema.Blob, Converter[[16]byte]{
+		ToColumn:   func(v [16]byte) (any, error) { return v[:], nil },
+		FromColumn: parse_uuid,
+	})
+	RegisterColumnConverter(schema.UUID, Converter[[16]byte]{
+		ToColumn:   func(v [16]byte) (any, error) { return format_uuid(v), nil },
+		FromColumn: parse_uuid,
+	})
+	RegisterColumnConverter(schema.UUID, Converter[string]{
+		ToColumn: func(v string) (any, error) { return v, nil },
+		FromColumn: func(src any, dst *string) error {
+			switch x := src.(type) {
+			case nil:
+				*dst = ""
+			case string:
+				*dst = x
+			case []byte:
+				if len(x) != 16 {
+					return fmt.Errorf("invalid uuid value %q", x)
+				}
+				*dst = format_uuid([16]byte(x))
+			default:
+				return fmt.Errorf("can not read uuid from %T", src)
+			}
+			return nil
+		},
+	})
+
+	RegisterColumnConverter(schema.Bool, Converter[bool]{
+		ToColumn: func(v bool) (any, error) { return v, nil },
+		FromColumn: func(src any, dst *bool) error {
+			switch x := src.(type) {
+			case nil:
+				*dst = false
+			case bool:
+				*dst = x
+			case int64:
+				*dst = x != 0
+			case float64:
+				*dst = x != 0
+			case []byte:
+				return parse_bool(string(x), dst)
+			case string:
+				return parse_bool(x, dst)
+			default:
+				return fmt.Errorf("can not read bool from %T", src)
+			}
+			return nil
+		},
+	})
+}
+
+func parse_bool(s string, dst *bool) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("invalid bool value %q", s)
+	}
+	*dst = v
+	return nil
+}
@@ -0,0 +1,85 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+func ExampleRegisterConverter() {
+
+	type Event struct {
+		ID      [16]byte          `orm:"id"`
+		At      time.Time         `orm:"at"`
+		Day     time.Time         `orm:"day"`
+		Done    bool              `orm:"done"`
+		Ends    *time.Time        `orm:"?ends"`
+		Labels  map[string]string `orm:"labels,json"`
+		Comment string            `orm:"comment"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table events (id uuid not null primary key, at text, day date, done bool, ends text, labels text, comment text)`)
+
+	events, err := GetTable(db, "events")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	at := time.Date(2024, 3, 1, 10, 30, 0, 0, time.UTC)
+	Insert(db, events, &Event{
+		ID:      [16]byte{0x12, 0x34, 15: 0xff},
+		At:      at,
+		Day:     at,
+		Done:    true,
+		Labels:  map[string]string{"k": "v"},
+		Comment: "first",
+	})
+
+	var id, day, labels string
+	var ends any
+	db.QueryRow(`select id, day || '', ends, labels from events`).Scan(&id, &day, &ends, &labels)
+	fmt.Println(id, day, ends, labels)
+
+	Select(db, events, Enumerate(), func(e *Event) error {
+		fmt.Println(e.ID[0], e.At.Equal(at), e.Day.Format(DateLayout), e.Done, e.Ends, e.Labels["k"])
+		return nil
+	})
+
+	// Output:
+	// 12340000-0000-0000-0000-0000000000ff 2024-03-01 <nil> {"k":"v"}
+	// 18 true 2024-03-01 true <nil> v
+}
+
+func ExampleRegisterColumnConverter() {
+
+	type File struct {
+		Name string   `orm:"name"`
+		Hash [16]byte `orm:"hash"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table blobs (name text, hash blob)`)
+	db.Exec(`create table texts (name text, hash text)`)
+
+	blobs, _ := GetTable(db, "blobs")
+	texts, _ := GetTable(db, "texts")
+
+	f := &File{Name: "a", Hash: [16]byte{0xab, 15: 0xcd}}
+	_, err := Insert(db, blobs, f)
+	fmt.Println(err)
+	_, err = Insert(db, texts, f)
+	fmt.Println(err != nil)
+
+	var hash []byte
+	db.QueryRow(`select hash from blobs`).Scan(&hash)
+	fmt.Printf("%x\n", hash)
+
+	// Output:
+	// <nil>
+	// true
+	// ab0000000000000000000000000000cd
+}
@@ -1,7 +1,16 @@
 package orm
 
-import "bytes"
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
 
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Options produce the select statement. The table name and selectors are
+// passed to Sql as sql text, names are quoted by the caller.
 type Options interface {
 	Sql(tablename string, selectors []string) string
 	Args() []any
@@ -11,14 +20,34 @@ type Condition interface {
 	Sql() (sql string, args []any)
 }
 
+// Validator is implemented by Options that can check the columns they refer
+// to before the query is built.
+type Validator interface {
+	Validate(table *Table) error
+}
+
+// Enumerate produces Options from the list of conditions. The clauses are
+// placed in the sql order regardless of the order of conditions:
+//
+//   - expressions (see Expr) are combined into a single where clause with 'and'
+//   - other custom conditions follow in the specified order
+//   - then group by, having, order by, limit and offset
+//
+// Duplicate group by, having, limit and offset clauses, as well as ordering
+// by the same column twice, are reported when the query is validated.
 func Enumerate(conditions ...Condition) Options {
 	enm := &enumerate_opts{}
 	for _, c := range conditions {
-		s, args := c.Sql()
-		if s != "" {
-			enm.sql_tail += " " + s
+		switch x := c.(type) {
+		case clause:
+			if err := x.apply(enm); err != nil && enm.err == nil {
+				enm.err = err
+			}
+		case Expr:
+			enm.exprs = append(enm.exprs, x)
+		default:
+			enm.custom = append(enm.custom, c)
 		}
-		enm.args = append(enm.args, args...)
 	}
 	return enm
 }
@@ -32,13 +61,136 @@ func (w *where) Sql() (sql string, args []any) {
 	return "where " + w.expr, w.args
 }
 
-func Where(expr string, args ...any) Condition {
+func (w *where) Expr() (sql string, args []any) { return w.expr, w.args }
+func (w *where) Columns() []string              { return nil }
+
+// Where produces a condition from a raw sql expression. Columns referenced in
+// raw expressions are not validated.
+func Where(expr string, args ...any) Expr {
 	return &where{expr: expr, args: args}
 }
 
+// Direction specifies sort order in OrderBy.
+type Direction int
+
+const (
+	Asc Direction = iota
+	Desc
+)
+
+// clause is a Condition that is placed at the fixed position in the query.
+type clause interface {
+	Condition
+	apply(enm *enumerate_opts) error
+}
+
+type order_term struct {
+	column string
+	dir    Direction
+}
+
+type order_by struct{ order_term }
+type group_by struct{ columns namelist }
+type having struct{ where }
+type limit struct{ n int64 }
+type offset struct{ n int64 }
+
+// OrderBy sorts the results by the column. Multiple OrderBy conditions
+// produce a multi-column sort in the order they are specified.
+func OrderBy(column string, dir Direction) Condition {
+	return &order_by{order_term{column: column, dir: dir}}
+}
+
+// GroupBy groups the results by the specified columns.
+func GroupBy(columns ...string) Condition {
+	return &group_by{columns: columns}
+}
+
+// Having filters groups with a raw sql expression.
+func Having(expr string, args ...any) Condition {
+	return &having{where{expr: expr, args: args}}
+}
+
+// Limit restricts the number of results.
+func Limit(n int64) Condition {
+	return &limit{n: n}
+}
+
+// Offset skips the specified number of results.
+func Offset(n int64) Condition {
+	return &offset{n: n}
+}
+
+func (c *order_by) Sql() (string, []any) { return "order by " + c.order_term.sql(), nil }
+func (c *group_by) Sql() (string, []any) { return "group by " + joined(quoted(c.columns)), nil }
+func (c *having) Sql() (string, []any)   { return "having " + c.expr, c.args }
+func (c *limit) Sql() (string, []any)    { return "limit " + strconv.FormatInt(c.n, 10), nil }
+func (c *offset) Sql() (string, []any)   { return "offset " + strconv.FormatInt(c.n, 10), nil }
+
+func (t *order_term) sql() string {
+	if t.dir == Desc {
+		return schema.QuoteName(t.column) + " desc"
+	}
+	return schema.QuoteName(t.column)
+}
+
+var ErrDuplicateClause = errors.New("duplicate clause")
+
+func duplicate(what string) error {
+	return fmt.Errorf("%w: %s", ErrDuplicateClause, what)
+}
+
+func (c *order_by) apply(enm *enumerate_opts) error {
+	for _, t := range enm.order {
+		if t.column == c.column {
+			return duplicate("order by " + c.column)
+		}
+	}
+	enm.order = append(enm.order, c.order_term)
+	return nil
+}
+
+func (c *group_by) apply(enm *enumerate_opts) error {
+	if enm.group_by != nil {
+		return duplicate("group by")
+	}
+	enm.group_by = c
+	return nil
+}
+
+func (c *having) apply(enm *enumerate_opts) error {
+	if enm.having != nil {
+		return duplicate("having")
+	}
+	enm.having = c
+	return nil
+}
+
+func (c *limit) apply(enm *enumerate_opts) error {
+	if enm.limit != nil {
+		return duplicate("limit")
+	}
+	enm.limit = c
+	return nil
+}
+
+func (c *offset) apply(enm *enumerate_opts) error {
+	if enm.offset != nil {
+		return duplicate("offset")
+	}
+	enm.offset = c
+	return nil
+}
+
 type enumerate_opts struct {
-	sql_tail string
-	args     []any
+	exprs    []Expr
+	custom   []Condition
+	group_by *group_by
+	having   *having
+	order    []order_term
+	limit    *limit
+	offset   *offset
+	err      error
 }
 
 func (enm *enumerate_opts) Sql(tablename string, selectors []string) string {
@@ -53,11 +205,100 @@ func (enm *enumerate_opts) Sql(tablename string, selectors []string) string {
 	b.WriteString(" from ")
 	b.WriteString(tablename)
 
-	if enm.sql_tail != "" {
-		b.WriteByte(' ')
-		b.WriteString(enm.sql_tail)
-	}
+	enm.write_clauses(func(s string, args []any) {
+		if s != "" {
+			b.WriteByte(' ')
+			b.WriteString(s)
+		}
+	})
 	return b.String()
 }
 
-func (e *enumerate_opts) Args() []any { return e.args }
+func (enm *enumerate_opts) Args() []any {
+	all := []any{}
+	enm.write_clauses(func(s string, args []any) {
+		all = append(all, args...)
+	})
+	return all
+}
+
+// write_clauses enumerates the clauses in the sql order.
+func (enm *enumerate_opts) write_clauses(on_clause func(s string, args []any)) {
+	if len(enm.exprs) > 0 {
+		on_clause(And(enm.exprs...).Sql())
+	}
+	for _, c := range enm.custom {
+		on_clause(c.Sql())
+	}
+	if enm.group_by != nil {
+		on_clause(enm.group_by.Sql())
+	}
+	if enm.having != nil {
+		on_clause(enm.having.Sql())
+	}
+	if len(enm.order) > 0 {
+		terms := make(namelist, len(enm.order))
+		for i := range enm.order {
+			terms[i] = enm.order[i].sql()
+		}
+		on_clause("order by "+joined(terms), nil)
+	}
+	if enm.limit != nil {
+		on_clause(enm.limit.Sql())
+	} else if enm.offset != nil {
+		// sqlite requires limit for offset
+		on_clause("limit -1", nil)
+	}
+	if enm.offset != nil {
+		on_clause(enm.offset.Sql())
+	}
+}
+
+// Validate checks that the columns referenced in the expressions and clauses
+// exist in the table, and that there are no duplicate clauses.
+func (e *enumerate_opts) Validate(table *Table) error {
+	if e.err != nil {
+		return e.err
+	}
+	referenced := namelist{}
+	for _, x := range e.exprs {
+		referenced = append(referenced, x.Columns()...)
+	}
+	if e.group_by != nil {
+		referenced = append(referenced, e.group_by.columns...)
+	}
+	for _, t := range e.order {
+		referenced = append(referenced, t.column)
+	}
+
+	missing := namelist{}
+	for _, c := range referenced {
+		if !table.HasColumn(c) && !contains(missing, c) {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) > 0 {
+		return ErrMissingColumns(missing)
+	}
+	return nil
+}
+
+// quoted applies schema.QuoteName to each of the names.
+func quoted(names namelist) namelist {
+	r := make(namelist, len(names))
+	for i, n := range names {
+		r[i] = schema.QuoteName(n)
+	}
+	return r
+}
+
+func joined(names namelist) string {
+	b := bytes.Buffer{}
+	for i, n := range names {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(n)
+	}
+	return b.String()
+}
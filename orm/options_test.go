@@ -0,0 +1,53 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func ExampleEnumerate() {
+
+	opts := Enumerate(
+		Limit(10),
+		OrderBy("total", Desc),
+		Having("sum(amount) > ?", 100),
+		Offset(20),
+		GroupBy("customer"),
+		Eq("status", "paid"),
+		OrderBy("customer", Asc),
+	)
+	fmt.Println(opts.Sql("orders", []string{"customer", "sum(amount) as total"}))
+	fmt.Println(opts.Args())
+
+	fmt.Println(Enumerate(Offset(5)).Sql("t", []string{"a"}))
+
+	type Row struct {
+		N int `orm:"n"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table t (n int); insert into t values (3), (1), (2), (5), (4);`)
+	t, _ := GetTable(db, "t")
+
+	Select(db, t, Enumerate(Offset(1), Limit(3), OrderBy("n", Desc)), func(r *Row) error {
+		fmt.Println(r.N)
+		return nil
+	})
+
+	err := Select(db, t, Enumerate(Limit(1), Limit(2)), func(r *Row) error { return nil })
+	fmt.Println(err, errors.Is(err, ErrDuplicateClause))
+
+	err = Select(db, t, Enumerate(OrderBy("m", Asc)), func(r *Row) error { return nil })
+	fmt.Println(err)
+
+	// Output:
+	// select customer, sum(amount) as total from orders where status = ? group by customer having sum(amount) > ? order by total desc, customer limit 10 offset 20
+	// [paid 100]
+	// select a from t limit -1 offset 5
+	// 4
+	// 3
+	// 2
+	// querying table t: duplicate clause: limit true
+	// querying table t: missing columns: m
+}
@@ -0,0 +1,210 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+var ErrUnsupportedOptions = errors.New("unsupported options")
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Paginate reads a page of up to page_size rows that follow the cursor. Pass
+// an empty cursor to read the first page. It returns an opaque cursor for the
+// next page, which is empty when there are no more rows.
+//
+// Pages are produced with keyset pagination: rows are ordered by the key and
+// filtered with 'where (k1, k2) > (?, ?)' instead of using offsets. The key is
+// the primary key of the table, or the columns specified with OrderBy
+// conditions in opts. A custom key must be unique and non-null, and all its
+// columns must be sorted in the same direction.
+//
+// Opts must be either nil or produced by Enumerate, Limit and Offset clauses
+// are not supported.
+func Paginate[T any](src Querier, table *Table, opts Options, page_size int, cursor string) (page []*T, next string, err error) {
+	return PaginateContext[T](context.Background(), querier_with_context(src), table, opts, page_size, cursor)
+}
+
+// PaginateContext is the context-aware version of Paginate.
+func PaginateContext[T any](ctx context.Context, src QuerierContext, table *Table, opts Options, page_size int, cursor string) (page []*T, next string, err error) {
+	enm, key, err := paginated(table, opts, page_size)
+	if err != nil {
+		return nil, "", fmt.Errorf("querying table %s: %w", table.Name, err)
+	}
+
+	if cursor != "" {
+		values, err := decode_cursor(cursor, len(key))
+		if err != nil {
+			return nil, "", fmt.Errorf("querying table %s: %w", table.Name, err)
+		}
+		enm.exprs = append(enm.exprs, key_after(key, values))
+	}
+
+	var internal_v T
+	bb, err := table.bind_receivers(&internal_v)
+	if err != nil {
+		return nil, "", fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+
+	// key values are selected once again as raw values to produce the cursor
+	keyed := &bindings{
+		selectors: append(namelist{}, bb.selectors...),
+		receivers: append([]any{}, bb.receivers...),
+	}
+	key_values := make([]any, len(key))
+	for i := range key {
+		keyed.selectors = append(keyed.selectors, key[i].column)
+		keyed.receivers = append(keyed.receivers, &key_values[i])
+	}
+
+	var last []any
+	err = select_rows(ctx, src, table, enm, keyed, func() error {
+		if len(page) == page_size {
+			next, err = encode_cursor(last)
+			return err
+		}
+		copy := internal_v
+		page = append(page, &copy)
+		last = append(last[:0], key_values...)
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+// paginated produces a copy of enumeration options with the key ordering and
+// the limit that fetches one extra row.
+func paginated(table *Table, opts Options, page_size int) (*enumerate_opts, []order_term, error) {
+	if page_size < 1 {
+		return nil, nil, fmt.Errorf("%w: invalid page size %d", ErrUnsupportedOptions, page_size)
+	}
+
+	enm := &enumerate_opts{}
+	if opts != nil {
+		src, ok := opts.(*enumerate_opts)
+		if !ok {
+			return nil, nil, fmt.Errorf("%w: pagination requires options produced by Enumerate", ErrUnsupportedOptions)
+		}
+		*enm = *src
+		enm.exprs = append([]Expr{}, src.exprs...)
+	}
+	if enm.limit != nil || enm.offset != nil {
+		return nil, nil, fmt.Errorf("%w: limit and offset can not be used with pagination", ErrUnsupportedOptions)
+	}
+
+	key := enm.order
+	if len(key) == 0 {
+		if len(table.pk) == 0 {
+			return nil, nil, ErrNoPrimaryKey
+		}
+		for _, n := range table.pk {
+			key = append(key, order_term{column: n, dir: Asc})
+		}
+		enm.order = key
+	}
+	for _, t := range key[1:] {
+		if t.dir != key[0].dir {
+			return nil, nil, fmt.Errorf("%w: mixed sort directions in pagination key", ErrUnsupportedOptions)
+		}
+	}
+	enm.limit = &limit{n: int64(page_size) + 1}
+	return enm, key, nil
+}
+
+// key_after produces '(k1, k2) > (?, ?)' expression, or '<' for descending
+// keys.
+func key_after(key []order_term, values []any) Expr {
+	op := " > "
+	if key[0].dir == Desc {
+		op = " < "
+	}
+	columns := make(namelist, len(key))
+	for i := range key {
+		columns[i] = schema.QuoteName(key[i].column)
+	}
+	b := bytes.Buffer{}
+	if len(key) == 1 {
+		b.WriteString(columns[0] + op + "?")
+	} else {
+		b.WriteString("(" + joined(columns) + ")" + op + "(")
+		write_placeholders(&b, len(key))
+		b.WriteByte(')')
+	}
+	return &where{expr: b.String(), args: values}
+}
+
+// cursor_value is a type-tagged raw value as obtained from the driver.
+type cursor_value struct {
+	Int   *int64     `json:"i,omitempty"`
+	Float *float64   `json:"f,omitempty"`
+	Text  *string    `json:"s,omitempty"`
+	Blob  *[]byte    `json:"b,omitempty"`
+	Time  *time.Time `json:"t,omitempty"`
+	Bool  *bool      `json:"o,omitempty"`
+}
+
+func encode_cursor(values []any) (string, error) {
+	cc := make([]cursor_value, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case int64:
+			cc[i].Int = &x
+		case float64:
+			cc[i].Float = &x
+		case string:
+			cc[i].Text = &x
+		case []byte:
+			// a pointer keeps empty blobs apart from nulls
+			cc[i].Blob = &x
+		case time.Time:
+			cc[i].Time = &x
+		case bool:
+			cc[i].Bool = &x
+		case nil:
+		default:
+			return "", fmt.Errorf("%w: unsupported key value type %T", ErrInvalidCursor, v)
+		}
+	}
+	j, err := json.Marshal(cc)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(j), nil
+}
+
+func decode_cursor(s string, n int) ([]any, error) {
+	j, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	cc := []cursor_value{}
+	if json.Unmarshal(j, &cc) != nil || len(cc) != n {
+		return nil, ErrInvalidCursor
+	}
+	values := make([]any, n)
+	for i, c := range cc {
+		switch {
+		case c.Int != nil:
+			values[i] = *c.Int
+		case c.Float != nil:
+			values[i] = *c.Float
+		case c.Text != nil:
+			values[i] = *c.Text
+		case c.Blob != nil:
+			values[i] = *c.Blob
+		case c.Time != nil:
+			values[i] = *c.Time
+		case c.Bool != nil:
+			values[i] = *c.Bool
+		}
+	}
+	return values, nil
+}
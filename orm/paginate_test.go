@@ -0,0 +1,86 @@
+package orm
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+func ExamplePaginate() {
+
+	type Item struct {
+		Name string `orm:"name"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table items (shelf int, slot int, name text, primary key (shelf, slot));
+		insert into items values (1, 1, 'a'), (1, 2, 'b'), (2, 1, 'c'), (2, 2, 'd'), (3, 1, 'e');`)
+	t, _ := GetTable(db, "items")
+
+	cursor := ""
+	for {
+		page, next, err := Paginate[Item](db, t, nil, 2, cursor)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		for _, it := range page {
+			fmt.Print(it.Name, " ")
+		}
+		fmt.Println("| more:", next != "")
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	page, next, _ := Paginate[Item](db, t, Enumerate(Gt("shelf", 1), OrderBy("name", Desc)), 2, "")
+	fmt.Println(len(page), page[0].Name, page[1].Name)
+	page, _, _ = Paginate[Item](db, t, Enumerate(Gt("shelf", 1), OrderBy("name", Desc)), 2, next)
+	fmt.Println(len(page), page[0].Name)
+
+	_, _, err := Paginate[Item](db, t, Enumerate(Limit(5)), 2, "")
+	fmt.Println(errors.Is(err, ErrUnsupportedOptions))
+	_, _, err = Paginate[Item](db, t, nil, 2, "garbage")
+	fmt.Println(err)
+
+	// Output:
+	// a b | more: true
+	// c d | more: true
+	// e | more: false
+	// 2 e d
+	// 1 c
+	// true
+	// querying table items: invalid cursor
+}
+
+func ExamplePaginate_blobKeys() {
+
+	type File struct {
+		Hash []byte `orm:"hash"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table files (hash blob not null primary key) without rowid;
+		insert into files values (x''), (x'01'), (x'02');`)
+	t, _ := GetTable(db, "files")
+
+	cursor := ""
+	for {
+		page, next, err := Paginate[File](db, t, nil, 1, cursor)
+		if err != nil {
+			fmt.Println(err)
+			return
+		}
+		fmt.Printf("[%x] | more: %v\n", page[0].Hash, next != "")
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+
+	// Output:
+	// [] | more: true
+	// [01] | more: true
+	// [02] | more: false
+}
@@ -0,0 +1,89 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"iter"
+	"reflect"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Query is a prepared select statement for struct T. It is safe for
+// concurrent use: each call binds a separate instance of T.
+type Query[T any] struct {
+	table *Table
+	plan  *binding_plan
+	stmt  *sql.Stmt
+	args  []any
+}
+
+// Prepare binds struct T to the table, see Select for the binding rules, and
+// prepares the select statement produced by opts. The arguments of opts are
+// used unless other arguments are specified when the query is run.
+//
+// The query must be closed when no longer needed.
+func Prepare[T any](src Preparer, table *Table, opts Options) (*Query[T], error) {
+	return PrepareContext[T](context.Background(), preparer_with_context(src), table, opts)
+}
+
+// PrepareContext is the context-aware version of Prepare.
+func PrepareContext[T any](ctx context.Context, src PreparerContext, table *Table, opts Options) (*Query[T], error) {
+	plan, err := table.binding_plan(reflect.TypeFor[T]())
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	if v, ok := opts.(Validator); ok {
+		if err := v.Validate(table); err != nil {
+			return nil, fmt.Errorf("querying table %s: %w", table.Name, err)
+		}
+	}
+	stmt, err := src.PrepareContext(ctx, opts.Sql(schema.QuoteName(table.Name), quoted(plan.selectors)))
+	if err != nil {
+		return nil, fmt.Errorf("querying table %s: %w", table.Name, err)
+	}
+	return &Query[T]{table: table, plan: plan, stmt: stmt, args: opts.Args()}, nil
+}
+
+// Close releases the prepared statement.
+func (q *Query[T]) Close() error {
+	return q.stmt.Close()
+}
+
+// Select runs the query and calls on_row for each result, the value passed to
+// on_row is reused between rows.
+func (q *Query[T]) Select(on_row func(t *T) error, args ...any) error {
+	return q.SelectContext(context.Background(), on_row, args...)
+}
+
+// SelectContext is the context-aware version of Select.
+func (q *Query[T]) SelectContext(ctx context.Context, on_row func(t *T) error, args ...any) error {
+	var internal_v T
+	return q.run(ctx, &internal_v, args, func() error {
+		return on_row(&internal_v)
+	})
+}
+
+// Rows runs the query and produces an iterator over its results, see Rows.
+func (q *Query[T]) Rows(args ...any) iter.Seq2[*T, error] {
+	return q.RowsContext(context.Background(), args...)
+}
+
+// RowsContext is the context-aware version of Rows.
+func (q *Query[T]) RowsContext(ctx context.Context, args ...any) iter.Seq2[*T, error] {
+	return iterate(func(v *T, on_row func() error) error {
+		return q.run(ctx, v, args, on_row)
+	})
+}
+
+func (q *Query[T]) run(ctx context.Context, v *T, args []any, on_row func() error) error {
+	if len(args) == 0 {
+		args = q.args
+	}
+	rows, err := q.stmt.QueryContext(ctx, args...)
+	if err != nil {
+		return fmt.Errorf("querying table %s: %w", q.table.Name, err)
+	}
+	return scan_rows(ctx, q.table, rows, q.plan.bind(reflect.ValueOf(v).Elem()), on_row)
+}
@@ -0,0 +1,62 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+func ExamplePrepare() {
+
+	type Row struct {
+		N    int    `orm:"n"`
+		Name string `orm:"name"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table t (n int, name text); insert into t values (1, 'a'), (2, 'b'), (3, 'c');`)
+	t, _ := GetTable(db, "t")
+
+	q, err := Prepare[Row](db, t, Enumerate(Gt("n", 1)))
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	defer q.Close()
+
+	for r, err := range q.Rows() {
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Println(r.N, r.Name)
+	}
+
+	counts := make([]int, 3)
+	wg := sync.WaitGroup{}
+	for i := range counts {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.Select(func(r *Row) error {
+				counts[i]++
+				return nil
+			}, i)
+		}()
+	}
+	wg.Wait()
+	fmt.Println(counts)
+
+	// the struct fields are examined once per struct type and table columns
+	p1, _ := t.binding_plan(reflect.TypeFor[Row]())
+	t2, _ := GetTable(db, "t")
+	p2, _ := t2.binding_plan(reflect.TypeFor[Row]())
+	fmt.Println(p1 == p2)
+
+	// Output:
+	// 2 b
+	// 3 c
+	// [3 2 1]
+	// true
+}
@@ -0,0 +1,211 @@
+package orm
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"time"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Record is a table row produced by SelectRecords, it maps column names to
+// values typed according to the column types:
+//
+//   - int and int64 columns produce int64 values
+//   - float columns produce float64 values
+//   - bool columns produce bool values
+//   - text and uuid columns produce string values
+//   - blob columns produce []byte values
+//   - time, date and timestamp columns produce time.Time values
+//
+// Null values are stored as nil. Values in columns of other types, as well as
+// values that can not be represented by the column type, are kept as stored.
+type Record map[string]any
+
+// IsNull checks if the column value is null or missing.
+func (r Record) IsNull(column string) bool {
+	return r[column] == nil
+}
+
+// Int64 returns the integer value of the column.
+func (r Record) Int64(column string) (int64, bool) {
+	switch v := r[column].(type) {
+	case int64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	default:
+		return 0, false
+	}
+}
+
+// Float64 returns the floating point value of the column.
+func (r Record) Float64(column string) (float64, bool) {
+	switch v := r[column].(type) {
+	case float64:
+		return v, true
+	case int64:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
+
+// Bool returns the boolean value of the column.
+func (r Record) Bool(column string) (bool, bool) {
+	switch v := r[column].(type) {
+	case bool:
+		return v, true
+	case int64:
+		return v != 0, true
+	default:
+		return false, false
+	}
+}
+
+// String returns the text value of the column.
+func (r Record) String(column string) (string, bool) {
+	switch v := r[column].(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+// Bytes returns the blob value of the column.
+func (r Record) Bytes(column string) ([]byte, bool) {
+	switch v := r[column].(type) {
+	case []byte:
+		return v, true
+	case string:
+		return []byte(v), true
+	default:
+		return nil, false
+	}
+}
+
+// Time returns the time value of the column.
+func (r Record) Time(column string) (time.Time, bool) {
+	v, ok := r[column].(time.Time)
+	return v, ok
+}
+
+// SelectMaps enumerates table rows mapping all table columns to the keys of
+// a map, see Record for the types of values. A fresh map is produced for each
+// row.
+func SelectMaps(src Querier, table *Table, opts Options, on_row func(m map[string]any) error) error {
+	return SelectMapsContext(context.Background(), querier_with_context(src), table, opts, on_row)
+}
+
+// SelectMapsContext is the context-aware version of SelectMaps.
+func SelectMapsContext(ctx context.Context, src QuerierContext, table *Table, opts Options, on_row func(m map[string]any) error) error {
+	return SelectRecordsContext(ctx, src, table, opts, func(r Record) error {
+		return on_row(r)
+	})
+}
+
+// SelectRecords enumerates table rows as records. A fresh record is produced
+// for each row.
+func SelectRecords(src Querier, table *Table, opts Options, on_row func(r Record) error) error {
+	return SelectRecordsContext(context.Background(), querier_with_context(src), table, opts, on_row)
+}
+
+// SelectRecordsContext is the context-aware version of SelectRecords.
+func SelectRecordsContext(ctx context.Context, src QuerierContext, table *Table, opts Options, on_row func(r Record) error) error {
+	bb := &bindings{
+		selectors: table.names,
+		receivers: make([]any, len(table.names)),
+	}
+	dd := make([]dynamic, len(table.names))
+	for i, n := range table.names {
+		dd[i].scan = dynamic_scan(table.types[n])
+		bb.receivers[i] = &dd[i]
+	}
+
+	return select_rows(ctx, src, table, opts, bb, func() error {
+		r := make(Record, len(dd))
+		for i, n := range table.names {
+			r[n] = dd[i].value
+		}
+		return on_row(r)
+	})
+}
+
+// dynamic is a receiver that produces a value typed according to the column
+// type.
+type dynamic struct {
+	scan  func(src any) (any, error)
+	value any
+}
+
+func (r *dynamic) Scan(src any) error {
+	if src == nil {
+		r.value = nil
+		return nil
+	}
+	v, err := r.scan(src)
+	if err != nil {
+		v = scan_raw(src)
+	}
+	r.value = v
+	return nil
+}
+
+// dynamic_scan picks the conversion for the values of the column type.
+func dynamic_scan(column_type schema.ColumnType) func(src any) (any, error) {
+	switch column_type {
+	case schema.Int, schema.Int64:
+		return scan_as[int64]
+	case schema.Float:
+		return scan_as[float64]
+	case schema.Text:
+		return scan_as[string]
+	case schema.Blob:
+		return scan_as[[]byte]
+	case schema.Bool:
+		return convert_as[bool](column_type)
+	case schema.Time, schema.Date, schema.Timestamp:
+		return convert_as[time.Time](column_type)
+	case schema.UUID:
+		return convert_as[string](column_type)
+	default:
+		return func(src any) (any, error) { return scan_raw(src), nil }
+	}
+}
+
+// scan_as converts src with the standard database/sql conversion rules.
+func scan_as[T any](src any) (any, error) {
+	var n sql.Null[T]
+	err := n.Scan(src)
+	return n.V, err
+}
+
+// convert_as converts src with the converter registered for T and the column
+// type, falling back to scan_as.
+func convert_as[T any](column_type schema.ColumnType) func(src any) (any, error) {
+	c := find_converter(reflect.TypeFor[T](), column_type, false)
+	if c == nil {
+		return scan_as[T]
+	}
+	return func(src any) (any, error) {
+		var v T
+		err := c.from_column(src, reflect.ValueOf(&v).Elem())
+		return v, err
+	}
+}
+
+// scan_raw keeps the value as stored, blobs are copied as the driver may reuse
+// the buffer.
+func scan_raw(src any) any {
+	if b, ok := src.([]byte); ok {
+		return append([]byte{}, b...)
+	}
+	return src
+}
@@ -0,0 +1,40 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExampleSelectRecords() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table items (id int64 not null primary key, title text, price real, active bool, added date, data blob, extra);
+		insert into items values (1, 'pen', 1.5, 1, '2024-03-01', x'0102', 'x');
+		insert into items values (2, 'ink', null, 0, null, null, 7);
+	`)
+
+	items, _ := GetTable(db, "items")
+	fmt.Println(items.Columns())
+
+	SelectRecords(db, items, Enumerate(OrderBy("id", Asc)), func(r Record) error {
+		title, _ := r.String("title")
+		price, ok := r.Float64("price")
+		active, _ := r.Bool("active")
+		added, _ := r.Time("added")
+		fmt.Println(title, price, ok, active, added.Format(DateLayout), r.IsNull("data"), r["extra"])
+		return nil
+	})
+
+	SelectMaps(db, items, Enumerate(Where("id = ?", 1)), func(m map[string]any) error {
+		fmt.Printf("%T %T %v\n", m["id"], m["active"], m["data"])
+		return nil
+	})
+
+	// Output:
+	// [id title price active added data extra]
+	// pen 1.5 true true 2024-03-01 false x
+	// ink 0 false false 0001-01-01 true 7
+	// int64 bool [1 2]
+}
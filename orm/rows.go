@@ -0,0 +1,78 @@
+package orm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+)
+
+// errStopped is returned from on_row callbacks when the consumer of an
+// iterator stops early.
+var errStopped = errors.New("stopped")
+
+// Rows produces an iterator over table rows mapping its columns to fields in
+// struct T, see Select for the binding rules. The yielded value is reused
+// between iterations, use RowCopies to obtain a fresh value for each row.
+//
+// An error stops the iteration after it is yielded with a nil value. Breaking
+// out of the loop closes the underlying query.
+func Rows[T any](src Querier, table *Table, opts Options) iter.Seq2[*T, error] {
+	return RowsContext[T](context.Background(), querier_with_context(src), table, opts)
+}
+
+// RowsContext is the context-aware version of Rows.
+func RowsContext[T any](ctx context.Context, src QuerierContext, table *Table, opts Options) iter.Seq2[*T, error] {
+	return iterate(func(v *T, on_row func() error) error {
+		bb, err := table.bind_receivers(v)
+		if err != nil {
+			return fmt.Errorf("binding table %s: %w", table.Name, err)
+		}
+		return select_rows(ctx, src, table, opts, bb, on_row)
+	})
+}
+
+// RowCopies is similar to Rows, but yields a separate copy for each row.
+func RowCopies[T any](src Querier, table *Table, opts Options) iter.Seq2[*T, error] {
+	return RowCopiesContext[T](context.Background(), querier_with_context(src), table, opts)
+}
+
+// RowCopiesContext is the context-aware version of RowCopies.
+func RowCopiesContext[T any](ctx context.Context, src QuerierContext, table *Table, opts Options) iter.Seq2[*T, error] {
+	return copies(RowsContext[T](ctx, src, table, opts))
+}
+
+// iterate produces an iterator from a function that populates v and calls
+// on_row for each row.
+func iterate[T any](run func(v *T, on_row func() error) error) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		// internal temporary that gets populated with results from row.Scan
+		var internal_v T
+
+		err := run(&internal_v, func() error {
+			if !yield(&internal_v, nil) {
+				return errStopped
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errStopped) {
+			yield(nil, err)
+		}
+	}
+}
+
+// copies wraps the iterator to yield a separate copy for each row.
+func copies[T any](seq iter.Seq2[*T, error]) iter.Seq2[*T, error] {
+	return func(yield func(*T, error) bool) {
+		for v, err := range seq {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+			copy := *v
+			if !yield(&copy, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExampleRows() {
+
+	type Row struct {
+		N int `orm:"n"`
+	}
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table t (n int); insert into t values (1), (2), (3), (4);`)
+	t, _ := GetTable(db, "t")
+
+	for r, err := range Rows[Row](db, t, Enumerate(OrderBy("n", Asc))) {
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Println(r.N)
+		if r.N == 2 {
+			// closes the query, the only connection is available again
+			break
+		}
+	}
+
+	copies := []*Row{}
+	for r, err := range RowCopies[Row](db, t, Enumerate(Gt("n", 2))) {
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		copies = append(copies, r)
+	}
+	fmt.Println(len(copies), copies[0].N, copies[1].N)
+
+	for _, err := range Rows[Row](db, t, Enumerate(Gt("m", 2))) {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// 1
+	// 2
+	// 2 3 4
+	// querying table t: missing columns: m
+}
@@ -1,6 +1,13 @@
 package orm
 
-import "fmt"
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/adnsv/go-db3/schema"
+)
 
 // Select enumerates table rows mapping its columns to fields in struct T.
 //
@@ -13,6 +20,12 @@ import "fmt"
 //   - use `orm:"?"` for structural child filds to optionally link to their fields
 //
 func Select[T any](src Querier, table *Table, opts Options, on_row func(t *T) error) error {
+	return SelectContext(context.Background(), querier_with_context(src), table, opts, on_row)
+}
+
+// SelectContext enumerates table rows mapping its columns to fields in struct
+// T, see Select for details. The context is checked between rows.
+func SelectContext[T any](ctx context.Context, src QuerierContext, table *Table, opts Options, on_row func(t *T) error) error {
 	// internal temporary that gets populated with results from row.Scan
 	var internal_v T
 
@@ -22,63 +35,92 @@ func Select[T any](src Querier, table *Table, opts Options, on_row func(t *T) er
 		return fmt.Errorf("binding table %s: %w", table.Name, err)
 	}
 
-	// statement sql
-	select_sql := opts.Sql(table.Name, bb.selectors)
-	rows, err := src.Query(select_sql, opts.Args()...)
-	if err != nil {
-		return fmt.Errorf("querying table %s: %w", table.Name, err)
-	}
-	defer rows.Close()
-	for rows.Next() {
-		err := rows.Scan(bb.receivers...)
-		if err != nil {
-			return fmt.Errorf("scanning table %s: %w", table.Name, err)
-		}
-		err = on_row(&internal_v)
-		if err != nil {
-			return fmt.Errorf("scanning table %s: %w", table.Name, err)
-		}
-	}
-	return nil
+	return select_rows(ctx, src, table, opts, bb, func() error {
+		return on_row(&internal_v)
+	})
 }
 
 // SelectToSlice appends results of Select enumeration to dst
 func SelectToSlice[S ~[]*T, T any](src Querier, table *Table, opts Options, dst *S) error {
-	return Select(src, table, opts, func(v *T) error {
-		copy := *v
-		*dst = append(*dst, &copy)
-		return nil
-	})
+	return SelectToSliceContext(context.Background(), querier_with_context(src), table, opts, dst)
+}
+
+// SelectToSliceContext appends results of SelectContext enumeration to dst
+func SelectToSliceContext[S ~[]*T, T any](ctx context.Context, src QuerierContext, table *Table, opts Options, dst *S) error {
+	for v, err := range RowCopiesContext[T](ctx, src, table, opts) {
+		if err != nil {
+			return err
+		}
+		*dst = append(*dst, v)
+	}
+	return nil
 }
 
-// Selector produces an enumerating callable for struct T.
+// Selector produces an enumerating callable for struct T, see SelectorContext.
 func Selector[T any](src Querier, table *Table) (func(opts Options, callback func(t *T) error) error, error) {
-	// internal temporary that gets populated with results from row.Scan
-	var internal_v T
+	f, err := SelectorContext[T](querier_with_context(src), table)
+	if err != nil {
+		return nil, err
+	}
+	return func(opts Options, callback func(t *T) error) error {
+		return f(context.Background(), opts, callback)
+	}, nil
+}
 
-	// receivers references to bound fields in T
-	bb, err := table.bind_receivers(&internal_v)
+// SelectorContext produces a context-aware enumerating callable for struct T.
+// The callable is safe for concurrent and nested use: each invocation binds a
+// separate instance of T.
+func SelectorContext[T any](src QuerierContext, table *Table) (func(ctx context.Context, opts Options, callback func(t *T) error) error, error) {
+	plan, err := table.binding_plan(reflect.TypeFor[T]())
 	if err != nil {
 		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
 	}
 
-	f := func(opts Options, callback func(t *T) error) error {
-		rows, err := src.Query(opts.Sql(table.Name, bb.selectors), opts.Args()...)
-		if err != nil {
+	f := func(ctx context.Context, opts Options, callback func(t *T) error) error {
+		// internal temporary that gets populated with results from row.Scan
+		var internal_v T
+		bb := plan.bind(reflect.ValueOf(&internal_v).Elem())
+		return select_rows(ctx, src, table, opts, bb, func() error {
+			return callback(&internal_v)
+		})
+	}
+	return f, nil
+}
+
+// select_rows runs the query and scans each row into the bound receivers
+// before calling on_row.
+func select_rows(ctx context.Context, src QuerierContext, table *Table, opts Options, bb *bindings, on_row func() error) error {
+	if v, ok := opts.(Validator); ok {
+		if err := v.Validate(table); err != nil {
 			return fmt.Errorf("querying table %s: %w", table.Name, err)
 		}
-		defer rows.Close()
-		for rows.Next() {
-			err := rows.Scan(bb.receivers...)
-			if err != nil {
-				return fmt.Errorf("scanning table %s: %w", table.Name, err)
-			}
-			err = callback(&internal_v)
-			if err != nil {
-				return fmt.Errorf("scanning table %s: %w", table.Name, err)
-			}
+	}
+	rows, err := src.QueryContext(ctx, opts.Sql(schema.QuoteName(table.Name), quoted(bb.selectors)), opts.Args()...)
+	if err != nil {
+		return fmt.Errorf("querying table %s: %w", table.Name, err)
+	}
+	return scan_rows(ctx, table, rows, bb, on_row)
+}
+
+// scan_rows scans each row into the bound receivers before calling on_row,
+// rows are closed on return.
+func scan_rows(ctx context.Context, table *Table, rows *sql.Rows, bb *bindings, on_row func() error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("scanning table %s: %w", table.Name, err)
+		}
+		err := rows.Scan(bb.receivers...)
+		if err != nil {
+			return fmt.Errorf("scanning table %s: %w", table.Name, err)
+		}
+		err = on_row()
+		if err != nil {
+			return fmt.Errorf("scanning table %s: %w", table.Name, err)
 		}
-		return nil
 	}
-	return f, nil
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("scanning table %s: %w", table.Name, err)
+	}
+	return nil
 }
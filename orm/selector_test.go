@@ -0,0 +1,132 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+type selector_row struct {
+	ID    int    `orm:"id"`
+	Name  string `orm:"name"`
+	Value int    `orm:"value"`
+}
+
+// open_wal creates a file database in WAL mode populated with n rows where
+// value is always id*10.
+func open_wal(t *testing.T, n int) (*sql.DB, *Table) {
+	t.Helper()
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "test.db")+"?_journal_mode=WAL&_busy_timeout=5000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	var mode string
+	if err = db.QueryRow("pragma journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Fatalf("journal mode %q: %v", mode, err)
+	}
+	if _, err = db.Exec("create table items (id integer primary key, name text, value int)"); err != nil {
+		t.Fatal(err)
+	}
+	rows := make([]*selector_row, n)
+	for i := range rows {
+		rows[i] = &selector_row{ID: i + 1, Name: fmt.Sprint("item", i+1), Value: (i + 1) * 10}
+	}
+	table, err := GetTable(db, "items")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = InsertMany(db, table, rows, &InsertManyOptions{Transaction: true}); err != nil {
+		t.Fatal(err)
+	}
+	return db, table
+}
+
+func check_row(r *selector_row) error {
+	if r.Value != r.ID*10 || r.Name != fmt.Sprint("item", r.ID) {
+		return fmt.Errorf("corrupted row %+v", *r)
+	}
+	return nil
+}
+
+func TestSelectorConcurrent(t *testing.T) {
+	db, table := open_wal(t, 200)
+	sel, err := Selector[selector_row](db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers = 8
+	wg := sync.WaitGroup{}
+	errs := make(chan error, workers+1) // readers and the writer send at most once
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				lo := (w*20 + i) % 150
+				count := 0
+				err := sel(Enumerate(Ge("id", lo), OrderBy("id", Asc)), func(r *selector_row) error {
+					count++
+					return check_row(r)
+				})
+				if err == nil && count != 200-max(lo, 1)+1 {
+					err = fmt.Errorf("got %d rows from %d", count, lo)
+				}
+				if err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+
+	// a concurrent writer that keeps the invariant
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			id := i%200 + 1
+			_, err := Update(db, table, &selector_row{ID: id, Name: fmt.Sprint("item", id), Value: id * 10})
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestSelectorNested(t *testing.T) {
+	db, table := open_wal(t, 10)
+	sel, err := Selector[selector_row](db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pairs := 0
+	err = sel(Enumerate(Le("id", 3)), func(outer *selector_row) error {
+		id := outer.ID
+		return sel(Enumerate(Gt("id", id)), func(inner *selector_row) error {
+			if outer.ID != id {
+				return fmt.Errorf("outer row changed from %d to %d", id, outer.ID)
+			}
+			pairs++
+			return check_row(inner)
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pairs != 9+8+7 {
+		t.Errorf("got %d pairs", pairs)
+	}
+}
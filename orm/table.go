@@ -1,10 +1,16 @@
 package orm
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+
+	"github.com/adnsv/go-db3/schema"
 )
 
 type namelist = []string
@@ -16,16 +22,32 @@ type Querier interface {
 }
 
 type Table struct {
-	Name    string
-	columns nameset
+	Name     string
+	names    namelist // columns in table order
+	columns  nameset
+	types    map[string]schema.ColumnType
+	computed nameset // generated columns, read-only
+	pk       namelist
+	rowid    string // primary key column that is an alias for the rowid
+	unique   map[string]namelist
+
+	signature_once  sync.Once
+	signature_value string
 }
 
 // GetTable queries table columns from src.
 func GetTable(src Querier, table_name string) (*Table, error) {
-	table := Table{Name: table_name, columns: nameset{}}
+	return GetTableContext(context.Background(), querier_with_context(src), table_name)
+}
+
+// GetTableContext queries table columns from src.
+func GetTableContext(ctx context.Context, src QuerierContext, table_name string) (*Table, error) {
+	table := Table{Name: table_name, columns: nameset{}, types: map[string]schema.ColumnType{}, computed: nameset{}}
+	pk_positions := map[string]int{}
+	integer_pk := false
 
-	q := "pragma table_info([" + table_name + "])"
-	err := query(src, q, nil,
+	q := "pragma table_xinfo(" + schema.QuoteName(table_name) + ")"
+	err := query(ctx, src, q, nil,
 		func(row *sql.Rows) error {
 			var cid int
 			var name string
@@ -33,20 +55,46 @@ func GetTable(src Querier, table_name string) (*Table, error) {
 			var notnull int
 			var dfltValue sql.NullString
 			var pk int
-			err := row.Scan(&cid, &name, &typeName, &notnull, &dfltValue, &pk)
-			if err != nil {
+			var hidden int
+			err := row.Scan(&cid, &name, &typeName, &notnull, &dfltValue, &pk, &hidden)
+			if err != nil || hidden == 1 {
 				return err
 			}
+			if hidden == 2 || hidden == 3 {
+				table.computed[name] = struct{}{}
+			}
+			table.names = append(table.names, name)
 			table.columns[name] = struct{}{}
+			table.types[name] = schema.NormalizeType(schema.ColumnType(typeName))
+			if pk > 0 {
+				table.pk = append(table.pk, name)
+				pk_positions[name] = pk
+				integer_pk = strings.EqualFold(typeName, "integer")
+			}
 			return nil
 		})
 	if err != nil {
 		return nil, err
 	}
+	sort.SliceStable(table.pk, func(i, j int) bool {
+		return pk_positions[table.pk[i]] < pk_positions[table.pk[j]]
+	})
+
+	if len(table.pk) == 1 && integer_pk {
+		err = table.query_rowid_alias(ctx, src)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = table.query_unique_indices(ctx, src)
+	if err != nil {
+		return nil, err
+	}
 
 	if len(table.columns) == 0 {
 		var exists bool
-		err := query(src, "select exists(select 1 from sqlite_master where type='table' and name=?)",
+		err := query(ctx, src, "select exists(select 1 from sqlite_master where type='table' and name=?)",
 			[]any{table_name}, func(row *sql.Rows) error {
 				return row.Scan(&exists)
 			})
@@ -63,9 +111,78 @@ func GetTable(src Querier, table_name string) (*Table, error) {
 	return &table, nil
 }
 
-func all_table_names(src Querier) (map[string]struct{}, error) {
+// query_rowid_alias checks if the integer primary key is an alias for the
+// rowid, which is not the case for 'without rowid' tables.
+func (t *Table) query_rowid_alias(ctx context.Context, src QuerierContext) error {
+	without_rowid := false
+	err := query(ctx, src, "pragma table_list("+schema.QuoteName(t.Name)+")", nil, func(row *sql.Rows) error {
+		var schema string
+		var name string
+		var typ string
+		var ncol int
+		var wr int
+		var strict int
+		err := row.Scan(&schema, &name, &typ, &ncol, &wr, &strict)
+		if err == nil && schema == "main" {
+			without_rowid = wr == 1
+		}
+		return err
+	})
+	if err == nil && !without_rowid {
+		t.rowid = t.pk[0]
+	}
+	return err
+}
+
+// query_unique_indices collects the columns of non-partial unique indices.
+// Indices on expressions can not be named as conflict targets by columns and
+// are left out.
+func (t *Table) query_unique_indices(ctx context.Context, src QuerierContext) error {
+	names := namelist{}
+	err := query(ctx, src, "pragma index_list("+schema.QuoteName(t.Name)+")", nil, func(row *sql.Rows) error {
+		var seq int
+		var name string
+		var unique int
+		var origin string
+		var partial int
+		err := row.Scan(&seq, &name, &unique, &origin, &partial)
+		if err == nil && unique == 1 && partial == 0 {
+			names = append(names, name)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	t.unique = make(map[string]namelist, len(names))
+	for _, n := range names {
+		columns := namelist{}
+		expressions := false
+		err := query(ctx, src, "pragma index_info("+schema.QuoteName(n)+")", nil, func(row *sql.Rows) error {
+			var seqno int
+			var cid int
+			var name sql.NullString
+			err := row.Scan(&seqno, &cid, &name)
+			if err == nil {
+				columns = append(columns, name.String)
+				expressions = expressions || !name.Valid
+			}
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		if !expressions {
+			t.unique[n] = columns
+		}
+	}
+	return nil
+}
+
+func all_table_names(ctx context.Context, src QuerierContext) (map[string]struct{}, error) {
 	m := map[string]struct{}{}
-	err := query(src, "select name from sqlite_master where type='table'", nil, func(row *sql.Rows) error {
+	err := query(ctx, src, "select name from sqlite_master where type='table'", nil, func(row *sql.Rows) error {
 		var n string
 		err := row.Scan(&n)
 		if err == nil {
@@ -83,7 +200,14 @@ func all_table_names(src Querier) (map[string]struct{}, error) {
 //
 // Prefix table name with ? to make it optional.
 func GetTables(src Querier, table_names ...string) (tt map[string]*Table, err error) {
-	existing, err := all_table_names(src)
+	return GetTablesContext(context.Background(), querier_with_context(src), table_names...)
+}
+
+// GetTablesContext queries the set of tables from src.
+//
+// Prefix table name with ? to make it optional.
+func GetTablesContext(ctx context.Context, src QuerierContext, table_names ...string) (tt map[string]*Table, err error) {
+	existing, err := all_table_names(ctx, src)
 	if err != nil {
 		return
 	}
@@ -115,7 +239,7 @@ func GetTables(src Querier, table_names ...string) (tt map[string]*Table, err er
 
 	tt = map[string]*Table{}
 	for n := range matching {
-		t, err := GetTable(src, n)
+		t, err := GetTableContext(ctx, src, n)
 		if err != nil {
 			return nil, err
 		}
@@ -130,12 +254,67 @@ func (t *Table) HasColumn(column_name string) bool {
 	return ok
 }
 
+// Columns returns the column names in table order.
+func (t *Table) Columns() []string {
+	return t.names
+}
+
+// ColumnType returns the normalized type of the column, see
+// schema.NormalizeType.
+func (t *Table) ColumnType(column_name string) schema.ColumnType {
+	return t.types[column_name]
+}
+
+// IsGenerated checks if the column is a generated column. Generated columns
+// are read-only, they are skipped when rows are inserted or updated.
+func (t *Table) IsGenerated(column_name string) bool {
+	_, ok := t.computed[column_name]
+	return ok
+}
+
+// RowIDAlias returns the integer primary key column that is an alias for the
+// rowid, or an empty string.
+func (t *Table) RowIDAlias() string {
+	return t.rowid
+}
+
+// PK returns the primary key columns of the table.
+func (t *Table) PK() []string {
+	return t.pk
+}
+
+// UniqueIndex returns the columns of the named unique index.
+func (t *Table) UniqueIndex(index_name string) ([]string, bool) {
+	cc, ok := t.unique[index_name]
+	return cc, ok
+}
+
 type bindings struct {
 	receivers []interface{}
 	selectors namelist
 	missing   namelist
 }
 
+// binding_plan is the cached result of matching the fields of a struct type
+// to the table columns: selectors, the index paths of the bound fields and
+// their converters.
+type binding_plan struct {
+	selectors  namelist
+	paths      [][]int
+	converters []field_converter
+	missing    namelist
+	err        error
+}
+
+type binding_key struct {
+	struct_t   reflect.Type
+	columns    string
+	converters uint64
+}
+
+// binding_cache maps binding_key to *binding_plan.
+var binding_cache sync.Map
+
 // bind_receivers creates a list of receivers for filds in dst that match the orm description.
 func (t *Table) bind_receivers(dst any) (*bindings, error) {
 	dst_v := reflect.ValueOf(dst)
@@ -148,29 +327,79 @@ func (t *Table) bind_receivers(dst any) (*bindings, error) {
 		panic("target must be a struct")
 	}
 
-	bb := &bindings{}
-	bind_struct_fields(t.columns, bb, false, &struct_value)
+	p, err := t.binding_plan(struct_value.Type())
+	if err != nil {
+		return nil, err
+	}
+	return p.bind(struct_value), nil
+}
 
-	if len(bb.missing) > 0 {
-		return nil, ErrMissingColumns(bb.missing)
-	} else if len(bb.selectors) == 0 {
+// binding_plan returns the cached plan for struct_t, the struct fields are
+// examined only once per struct type and a set of table columns.
+func (t *Table) binding_plan(struct_t reflect.Type) (*binding_plan, error) {
+	key := binding_key{struct_t: struct_t, columns: t.signature(), converters: converters_version.Load()}
+	cached, ok := binding_cache.Load(key)
+	if !ok {
+		p := &binding_plan{}
+		p.err = t.plan_struct_fields(p, false, struct_t, nil)
+		cached, _ = binding_cache.LoadOrStore(key, p)
+	}
+
+	p := cached.(*binding_plan)
+	if p.err != nil {
+		return nil, p.err
+	} else if len(p.missing) > 0 {
+		return nil, append(ErrMissingColumns{}, p.missing...)
+	} else if len(p.selectors) == 0 {
 		return nil, ErrNoBindingsProduced
 	}
+	return p, nil
+}
 
-	return bb, nil
+// signature identifies the set of table columns and their types.
+func (t *Table) signature() string {
+	t.signature_once.Do(func() {
+		names := make(namelist, 0, len(t.columns))
+		for n := range t.columns {
+			names = append(names, n+" "+string(t.types[n]))
+		}
+		sort.Strings(names)
+		t.signature_value = strings.Join(names, "\x00")
+	})
+	return t.signature_value
 }
 
-func bind_struct_fields(columns nameset, bb *bindings, all_optional bool, struct_v *reflect.Value) {
-	for i := 0; i < struct_v.NumField(); i++ {
-		field_v := struct_v.Field(i)
-		field_t := struct_v.Type().Field(i)
+// bind produces receivers pointing to the fields of struct_v.
+func (p *binding_plan) bind(struct_v reflect.Value) *bindings {
+	n := len(p.selectors)
+	bb := &bindings{
+		selectors: p.selectors[:n:n],
+		receivers: make([]any, len(p.paths)),
+	}
+	for i, path := range p.paths {
+		field_v := struct_v.FieldByIndex(path)
+		if c := p.converters[i]; c != nil {
+			bb.receivers[i] = &converting{conv: c, field: field_v}
+		} else {
+			bb.receivers[i] = field_v.Addr().Interface()
+		}
+	}
+	return bb
+}
+
+func (t *Table) plan_struct_fields(p *binding_plan, all_optional bool, struct_t reflect.Type, prefix []int) error {
+	for i := 0; i < struct_t.NumField(); i++ {
+		field_t := struct_t.Field(i)
+		path := append(prefix[:len(prefix):len(prefix)], i)
 
 		tag := field_t.Tag
 		orm_content := tag.Get("orm")
 
 		if field_t.Type.Kind() == reflect.Struct && (orm_content == "!" || orm_content == "?") {
 			optional := all_optional || orm_content == "?"
-			bind_struct_fields(columns, bb, optional, &field_v)
+			if err := t.plan_struct_fields(p, optional, field_t.Type, path); err != nil {
+				return err
+			}
 			continue
 		}
 
@@ -178,12 +407,25 @@ func bind_struct_fields(columns nameset, bb *bindings, all_optional bool, struct
 			continue
 		}
 
-		optional := orm_content[0] == '?'
+		as_json := false
+		if n := strings.IndexByte(orm_content, ','); n >= 0 {
+			for _, option := range strings.Split(orm_content[n+1:], ",") {
+				switch option {
+				case "json":
+					as_json = true
+				default:
+					return fmt.Errorf("invalid orm tag option %q in field %s", option, field_t.Name)
+				}
+			}
+			orm_content = orm_content[:n]
+		}
+
+		optional := strings.HasPrefix(orm_content, "?")
 		if optional {
 			orm_content = orm_content[1:]
-			if orm_content == "" {
-				panic("invalid orm tag syntax " + orm_content + " in field " + field_t.Name)
-			}
+		}
+		if orm_content == "" {
+			return fmt.Errorf("invalid orm tag %q in field %s", tag.Get("orm"), field_t.Name)
 		}
 		if all_optional {
 			optional = true
@@ -191,7 +433,7 @@ func bind_struct_fields(columns nameset, bb *bindings, all_optional bool, struct
 
 		orm := ""
 		for _, orm_term := range strings.Split(orm_content, "|") {
-			if _, exists := columns[orm_term]; exists {
+			if _, exists := t.columns[orm_term]; exists {
 				orm = orm_term
 				break
 			}
@@ -199,35 +441,44 @@ func bind_struct_fields(columns nameset, bb *bindings, all_optional bool, struct
 
 		if orm == "" {
 			if !optional {
-				bb.missing = append(bb.missing, orm_content)
+				p.missing = append(p.missing, orm_content)
 			}
 			continue
 		}
 
-		dst := field_v.Addr().Interface()
-		bb.receivers = append(bb.receivers, dst)
-		bb.selectors = append(bb.selectors, orm)
+		p.paths = append(p.paths, path)
+		p.selectors = append(p.selectors, orm)
+		p.converters = append(p.converters, find_converter(field_t.Type, t.types[orm], as_json))
 	}
+	return nil
 }
 
-func query(src Querier, q string, args []any, on_row func(row *sql.Rows) error) error {
-	rows, err := src.Query(q, args...)
+func query(ctx context.Context, src QuerierContext, q string, args []any, on_row func(row *sql.Rows) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rows, err := src.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		err = on_row(rows)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return rows.Err()
 }
 
 var ErrTableDoesNotExist = errors.New("table does not exist")
 var ErrEmptyTableSchema = errors.New("empty table schema")
 var ErrNoBindingsProduced = errors.New("failed to produce any field bindings")
+var ErrNoPrimaryKey = errors.New("table has no primary key")
+var ErrNoUniqueIndex = errors.New("unique index does not exist")
 
 // ErrMissingColumns is a list of column names that can be used as the 'missing
 // columns' error.
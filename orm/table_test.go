@@ -40,3 +40,28 @@ func Example_bind_receivers() {
 	// Output:
 	// ss: [f1 f2 f3 f5 f8]
 }
+
+func Example_bind_receivers_invalidTags() {
+
+	t := Table{Name: "t", columns: nameset{"f1": {}}}
+
+	_, err := t.bind_receivers(&struct {
+		F1 int `orm:",json"`
+	}{})
+	fmt.Println(err)
+
+	_, err = t.bind_receivers(&struct {
+		F1 int `orm:"?"`
+	}{})
+	fmt.Println(err)
+
+	_, err = t.bind_receivers(&struct {
+		F1 int `orm:"f1,jsn"`
+	}{})
+	fmt.Println(err)
+
+	// Output:
+	// invalid orm tag ",json" in field F1
+	// invalid orm tag "?" in field F1
+	// invalid orm tag option "jsn" in field F1
+}
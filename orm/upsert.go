@@ -0,0 +1,118 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// UpsertOptions control the conflict handling in Upsert.
+type UpsertOptions struct {
+	// Index names a unique index to use as the conflict target instead of
+	// the primary key.
+	Index string
+
+	// Exclude lists the columns that are not updated on conflict.
+	Exclude []string
+
+	// DoNothing skips conflicting rows instead of updating them.
+	DoNothing bool
+}
+
+// Upsert inserts a row with values taken from the fields of v, or updates the
+// existing row if the insertion conflicts on the primary key or on the unique
+// index specified in opts. On conflict, all bound columns except for the
+// conflict target and the excluded columns are updated.
+//
+// Nil opts are equivalent to zero UpsertOptions.
+func Upsert[T any](dst Execer, table *Table, v *T, opts *UpsertOptions) (sql.Result, error) {
+	return UpsertContext(context.Background(), execer_with_context(dst), table, v, opts)
+}
+
+// UpsertContext is the context-aware version of Upsert.
+func UpsertContext[T any](ctx context.Context, dst ExecerContext, table *Table, v *T, opts *UpsertOptions) (sql.Result, error) {
+	bb, err := table.bind_writable(v)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	q, err := upsert_sql(table, bb.selectors, opts)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	r, err := dst.ExecContext(ctx, q, bb.values()...)
+	if err != nil {
+		return nil, fmt.Errorf("upserting into table %s: %w", table.Name, err)
+	}
+	return r, nil
+}
+
+// conflict_target returns the columns that identify conflicting rows.
+func (opts *UpsertOptions) conflict_target(table *Table) (namelist, error) {
+	if opts != nil && opts.Index != "" {
+		cc, ok := table.UniqueIndex(opts.Index)
+		if !ok {
+			return nil, fmt.Errorf("%s: %w", opts.Index, ErrNoUniqueIndex)
+		}
+		return cc, nil
+	}
+	if len(table.pk) == 0 {
+		return nil, ErrNoPrimaryKey
+	}
+	return table.pk, nil
+}
+
+// upsert_clause produces the 'on conflict' clause for the specified inserted
+// columns.
+func (opts *UpsertOptions) upsert_clause(table *Table, selectors []string) (string, error) {
+	target, err := opts.conflict_target(table)
+	if err != nil {
+		return "", err
+	}
+
+	b := bytes.Buffer{}
+	b.WriteString(" on conflict(")
+	for i := range target {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(schema.QuoteName(target[i]))
+	}
+	b.WriteString(") do ")
+
+	updated := namelist{}
+	if opts == nil || !opts.DoNothing {
+		for _, s := range selectors {
+			if contains(target, s) || (opts != nil && contains(opts.Exclude, s)) {
+				continue
+			}
+			updated = append(updated, s)
+		}
+	}
+	if len(updated) == 0 {
+		b.WriteString("nothing")
+		return b.String(), nil
+	}
+
+	b.WriteString("update set ")
+	for i, s := range updated {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		n := schema.QuoteName(s)
+		b.WriteString(n)
+		b.WriteString(" = excluded.")
+		b.WriteString(n)
+	}
+	return b.String(), nil
+}
+
+func upsert_sql(table *Table, selectors []string, opts *UpsertOptions) (string, error) {
+	clause, err := opts.upsert_clause(table, selectors)
+	if err != nil {
+		return "", err
+	}
+	return insert_sql(table.Name, selectors, 1) + clause, nil
+}
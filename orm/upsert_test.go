@@ -0,0 +1,48 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExampleUpsert() {
+
+	type Item struct {
+		ID    int64  `orm:"id"`
+		SKU   string `orm:"sku"`
+		Title string `orm:"title"`
+		Count int    `orm:"count"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table items (id int64 not null primary key, sku text not null, title text, count int);
+		create unique index items_sku on items(sku);
+		create unique index items_lower_title on items(lower(title));
+	`)
+
+	items, _ := GetTable(db, "items")
+
+	Upsert(db, items, &Item{ID: 1, SKU: "a", Title: "first", Count: 1}, nil)
+	Upsert(db, items, &Item{ID: 1, SKU: "a", Title: "renamed", Count: 2}, nil)
+	Upsert(db, items, &Item{ID: 2, SKU: "a", Title: "by sku", Count: 3},
+		&UpsertOptions{Index: "items_sku", Exclude: []string{"id", "title"}})
+	Upsert(db, items, &Item{ID: 1, SKU: "a", Title: "ignored", Count: 4},
+		&UpsertOptions{DoNothing: true})
+
+	_, err := Upsert(db, items, &Item{}, &UpsertOptions{Index: "missing"})
+	fmt.Println(err)
+	_, err = Upsert(db, items, &Item{}, &UpsertOptions{Index: "items_lower_title"})
+	fmt.Println(err)
+
+	Select(db, items, Enumerate(), func(v *Item) error {
+		fmt.Println(v.ID, v.SKU, v.Title, v.Count)
+		return nil
+	})
+
+	// Output:
+	// binding table items: missing: unique index does not exist
+	// binding table items: items_lower_title: unique index does not exist
+	// 1 a renamed 3
+}
@@ -0,0 +1,267 @@
+package orm
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Execer is a generic db statement runner, typically should be hooked to sql.Tx
+// or sql.DB.
+type Execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Insert adds a row to the table with values taken from the fields of v. The
+// fields are bound to columns using the same orm tags as in Select, fields
+// bound to generated columns are skipped.
+//
+// When the primary key is an alias for the rowid (see Table.RowIDAlias) and
+// its integer field is zero, the key is generated by sqlite and the field
+// receives the value of LastInsertId.
+func Insert[T any](dst Execer, table *Table, v *T) (sql.Result, error) {
+	return InsertContext(context.Background(), execer_with_context(dst), table, v)
+}
+
+// InsertContext is the context-aware version of Insert.
+func InsertContext[T any](ctx context.Context, dst ExecerContext, table *Table, v *T) (sql.Result, error) {
+	bb, err := table.bind_writable(v)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	key, bb := bb.without_zero_key(table.rowid)
+	r, err := dst.ExecContext(ctx, insert_sql(table.Name, bb.selectors, 1), bb.values()...)
+	if err != nil {
+		return nil, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+	}
+	if key.IsValid() {
+		id, err := r.LastInsertId()
+		if err != nil {
+			return nil, fmt.Errorf("inserting into table %s: %w", table.Name, err)
+		}
+		set_key(key, id)
+	}
+	return r, nil
+}
+
+// Update modifies the row identified by the primary key values in v, setting
+// all the other bound columns from the fields of v.
+func Update[T any](dst Execer, table *Table, v *T) (sql.Result, error) {
+	return UpdateContext(context.Background(), execer_with_context(dst), table, v)
+}
+
+// UpdateContext is the context-aware version of Update.
+func UpdateContext[T any](ctx context.Context, dst ExecerContext, table *Table, v *T) (sql.Result, error) {
+	bb, err := table.bind_writable(v)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	key, rest, err := bb.split_key(table.pk)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	if len(rest.selectors) == 0 {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, ErrNoBindingsProduced)
+	}
+
+	b := bytes.Buffer{}
+	b.WriteString("update ")
+	b.WriteString(schema.QuoteName(table.Name))
+	b.WriteString(" set ")
+	write_assignments(&b, rest.selectors, ", ")
+	b.WriteString(" where ")
+	write_assignments(&b, key.selectors, " and ")
+
+	r, err := dst.ExecContext(ctx, b.String(), append(rest.values(), key.values()...)...)
+	if err != nil {
+		return nil, fmt.Errorf("updating table %s: %w", table.Name, err)
+	}
+	return r, nil
+}
+
+// Delete removes the row identified by the primary key values in v.
+func Delete[T any](dst Execer, table *Table, v *T) (sql.Result, error) {
+	return DeleteContext(context.Background(), execer_with_context(dst), table, v)
+}
+
+// DeleteContext is the context-aware version of Delete.
+func DeleteContext[T any](ctx context.Context, dst ExecerContext, table *Table, v *T) (sql.Result, error) {
+	bb, err := table.bind_receivers(v)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+	key, _, err := bb.split_key(table.pk)
+	if err != nil {
+		return nil, fmt.Errorf("binding table %s: %w", table.Name, err)
+	}
+
+	b := bytes.Buffer{}
+	b.WriteString("delete from ")
+	b.WriteString(schema.QuoteName(table.Name))
+	b.WriteString(" where ")
+	write_assignments(&b, key.selectors, " and ")
+
+	r, err := dst.ExecContext(ctx, b.String(), key.values()...)
+	if err != nil {
+		return nil, fmt.Errorf("deleting from table %s: %w", table.Name, err)
+	}
+	return r, nil
+}
+
+// insert_sql produces an insert statement with the specified number of value
+// rows.
+func insert_sql(tablename string, selectors []string, rows int) string {
+	b := bytes.Buffer{}
+	b.WriteString("insert into ")
+	b.WriteString(schema.QuoteName(tablename))
+	b.WriteString(" (")
+	for i := range selectors {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(schema.QuoteName(selectors[i]))
+	}
+	b.WriteString(") values ")
+	for r := 0; r < rows; r++ {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		write_placeholders(&b, len(selectors))
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+func write_placeholders(b *bytes.Buffer, n int) {
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('?')
+	}
+}
+
+func write_assignments(b *bytes.Buffer, selectors []string, sep string) {
+	for i := range selectors {
+		if i > 0 {
+			b.WriteString(sep)
+		}
+		b.WriteString(schema.QuoteName(selectors[i]))
+		b.WriteString(" = ?")
+	}
+}
+
+// values dereferences receivers to produce statement arguments.
+func (bb *bindings) values() []any {
+	vv := make([]any, len(bb.receivers))
+	for i, r := range bb.receivers {
+		if c, ok := r.(*converting); ok {
+			// converted by the driver through driver.Valuer
+			vv[i] = c
+		} else {
+			vv[i] = reflect.ValueOf(r).Elem().Interface()
+		}
+	}
+	return vv
+}
+
+// bind_writable binds the fields of v like bind_receivers, skipping the
+// generated columns.
+func (t *Table) bind_writable(v any) (*bindings, error) {
+	bb, err := t.bind_receivers(v)
+	if err != nil || len(t.computed) == 0 {
+		return bb, err
+	}
+	w := &bindings{}
+	for i, s := range bb.selectors {
+		if !t.IsGenerated(s) {
+			w.selectors = append(w.selectors, s)
+			w.receivers = append(w.receivers, bb.receivers[i])
+		}
+	}
+	return w, nil
+}
+
+// without_zero_key removes the binding of the rowid alias column if it refers
+// to an integer field with zero value. The field is returned to receive the
+// generated key, otherwise the returned value is invalid.
+func (bb *bindings) without_zero_key(rowid string) (reflect.Value, *bindings) {
+	if rowid == "" {
+		return reflect.Value{}, bb
+	}
+	for i, s := range bb.selectors {
+		if s != rowid {
+			continue
+		}
+		field := reflect.ValueOf(bb.receivers[i]).Elem()
+		if field.CanInt() && field.Int() == 0 || field.CanUint() && field.Uint() == 0 {
+			return field, bb.without(i)
+		}
+		break
+	}
+	return reflect.Value{}, bb
+}
+
+// set_key stores the generated key in the field returned by without_zero_key.
+func set_key(field reflect.Value, id int64) {
+	if field.CanUint() {
+		field.SetUint(uint64(id))
+	} else {
+		field.SetInt(id)
+	}
+}
+
+// without produces a copy of bindings with the i-th binding removed.
+func (bb *bindings) without(i int) *bindings {
+	w := &bindings{}
+	w.selectors = append(append(w.selectors, bb.selectors[:i]...), bb.selectors[i+1:]...)
+	w.receivers = append(append(w.receivers, bb.receivers[:i]...), bb.receivers[i+1:]...)
+	return w
+}
+
+// split_key separates bindings for key columns from the rest. All key columns
+// must be bound.
+func (bb *bindings) split_key(key namelist) (k *bindings, rest *bindings, err error) {
+	if len(key) == 0 {
+		return nil, nil, ErrNoPrimaryKey
+	}
+	k, rest = &bindings{}, &bindings{}
+	for _, n := range key {
+		found := false
+		for i, s := range bb.selectors {
+			if s == n {
+				k.selectors = append(k.selectors, s)
+				k.receivers = append(k.receivers, bb.receivers[i])
+				found = true
+				break
+			}
+		}
+		if !found {
+			k.missing = append(k.missing, n)
+		}
+	}
+	if len(k.missing) > 0 {
+		return nil, nil, ErrMissingColumns(k.missing)
+	}
+	for i, s := range bb.selectors {
+		if !contains(key, s) {
+			rest.selectors = append(rest.selectors, s)
+			rest.receivers = append(rest.receivers, bb.receivers[i])
+		}
+	}
+	return k, rest, nil
+}
+
+func contains(names namelist, n string) bool {
+	for _, s := range names {
+		if s == n {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func ExampleInsert() {
+
+	type Address struct {
+		City string `orm:"city"`
+	}
+
+	type User struct {
+		ID      int64   `orm:"id"`
+		Name    string  `orm:"name|full_name"`
+		Email   *string `orm:"?email"`
+		Address Address `orm:"!"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table users (id int64 not null primary key, full_name text, city text)`)
+
+	users, err := GetTable(db, "users")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	u := &User{ID: 1, Name: "alice", Address: Address{City: "Paris"}}
+	Insert(db, users, u)
+	Insert(db, users, &User{ID: 2, Name: "bob", Address: Address{City: "Rome"}})
+
+	u.Address.City = "Berlin"
+	r, err := Update(db, users, u)
+	n, _ := r.RowsAffected()
+	fmt.Println(n, err)
+
+	Delete(db, users, &User{ID: 2})
+
+	Select(db, users, Enumerate(), func(u *User) error {
+		fmt.Println(u.ID, u.Name, u.Address.City)
+		return nil
+	})
+
+	// Output:
+	// 1 <nil>
+	// 1 alice Berlin
+}
+
+func ExampleInsert_generatedColumns() {
+
+	type Item struct {
+		ID    int64   `orm:"id"`
+		Price float64 `orm:"price"`
+		Count int     `orm:"count"`
+		Total float64 `orm:"total"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table items (
+		id int64 not null primary key,
+		price real,
+		count int,
+		total real generated always as (price * count) stored
+	)`)
+
+	items, _ := GetTable(db, "items")
+	fmt.Println(items.IsGenerated("total"), items.IsGenerated("price"))
+
+	it := &Item{ID: 1, Price: 2.5, Count: 2, Total: -1}
+	_, err := Insert(db, items, it)
+	fmt.Println(err)
+
+	it.Count = 4
+	_, err = Update(db, items, it)
+	fmt.Println(err)
+
+	Select(db, items, Enumerate(), func(v *Item) error {
+		fmt.Println(v.ID, v.Total)
+		return nil
+	})
+
+	// Output:
+	// true false
+	// <nil>
+	// <nil>
+	// 1 10
+}
+
+func ExampleInsert_rowidAlias() {
+
+	type Note struct {
+		ID   int64  `orm:"id"`
+		Text string `orm:"text"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table notes (id integer primary key autoincrement, text text)`)
+
+	notes, _ := GetTable(db, "notes")
+	fmt.Println(notes.RowIDAlias())
+
+	a := &Note{Text: "first"}
+	Insert(db, notes, a)
+	b := &Note{ID: 10, Text: "explicit"}
+	Insert(db, notes, b)
+	c := &Note{Text: "next"}
+	Insert(db, notes, c)
+	fmt.Println(a.ID, b.ID, c.ID)
+
+	type UnsignedNote struct {
+		ID   uint16 `orm:"id"`
+		Text string `orm:"text"`
+	}
+	d := &UnsignedNote{Text: "unsigned"}
+	Insert(db, notes, d)
+	fmt.Println(d.ID)
+
+	// Output:
+	// id
+	// 1 10 11
+	// 12
+}
+
+func ExampleInsert_quotedNames() {
+
+	type Order struct {
+		Key   string `orm:"key"`
+		Group string `orm:"group"`
+		Total int    `orm:"grand total"`
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`create table "order" ("key" text primary key, "group" text, "grand total" int)`)
+
+	orders, err := GetTable(db, "order")
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	Insert(db, orders, &Order{Key: "a", Group: "x", Total: 1})
+	Insert(db, orders, &Order{Key: "b", Group: "y", Total: 2})
+	Update(db, orders, &Order{Key: "a", Group: "x", Total: 10})
+	Upsert(db, orders, &Order{Key: "b", Group: "z", Total: 20}, nil)
+
+	Select(db, orders, Enumerate(Eq("group", "z"), OrderBy("grand total", Desc)), func(o *Order) error {
+		fmt.Println(o.Key, o.Group, o.Total)
+		return nil
+	})
+	page, _, err := Paginate[Order](db, orders, Enumerate(OrderBy("key", Asc)), 1, "")
+	fmt.Println(page[0].Key, err)
+
+	// Output:
+	// b z 20
+	// a <nil>
+}
@@ -0,0 +1,141 @@
+package schema
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// ApplyOptions control how Apply brings the database in line with the data
+// model.
+type ApplyOptions struct {
+	// DryRun produces the statements without executing them.
+	DryRun bool
+
+	// Rebuild allows rebuilding tables that can not be altered in place.
+	Rebuild bool
+
+	// Drop removes tables, columns and indices that are absent from the data
+	// model.
+	Drop bool
+}
+
+// Apply scans the database schema, compares it with the data model and
+// executes the statements required to bring the database in line: missing
+// tables and indices are created, missing columns are added and, optionally,
+// incompatible tables are rebuilt. All statements are executed within a
+// single transaction. The statements are returned even if they are not
+// executed.
+//
+// Nil opts are equivalent to zero ApplyOptions.
+//
+// Foreign key enforcement, if enabled, is suspended while the statements are
+// executed and the result is verified with 'pragma foreign_key_check' before
+// committing.
+func Apply(db *sql.DB, want *Database, opts *ApplyOptions) ([]string, error) {
+	return ApplyContext(context.Background(), db, want, opts)
+}
+
+// ApplyContext is the context-aware version of Apply.
+func ApplyContext(ctx context.Context, db *sql.DB, want *Database, opts *ApplyOptions) ([]string, error) {
+	if opts == nil {
+		opts = &ApplyOptions{}
+	}
+	flags := []MigrateFlag{}
+	if !opts.Rebuild {
+		flags = append(flags, NoRebuild)
+	}
+	if !opts.Drop {
+		flags = append(flags, NoDrop)
+	}
+
+	// foreign key pragma is a no-op within transactions, so the whole thing
+	// runs on a dedicated connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	foreign_keys := false
+	if !opts.DryRun {
+		err = conn.QueryRowContext(ctx, "pragma foreign_keys").Scan(&foreign_keys)
+		if err != nil {
+			return nil, err
+		}
+		if foreign_keys {
+			if _, err = conn.ExecContext(ctx, "pragma foreign_keys = off"); err != nil {
+				return nil, err
+			}
+			// restored even if ctx is cancelled, the connection returns to the pool
+			defer conn.ExecContext(context.Background(), "pragma foreign_keys = on")
+		}
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	have, err := ScanContext(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+	stmts, err := PlanMigration(have, want, flags...)
+	if err != nil || opts.DryRun || len(stmts) == 0 {
+		return stmts, err
+	}
+
+	for _, s := range stmts {
+		if strings.HasPrefix(s, "pragma foreign_key_check") {
+			// checks of rebuilt tables, the violations are rows, not errors
+			if foreign_keys {
+				if err = foreign_key_check(ctx, tx, s); err != nil {
+					return stmts, err
+				}
+			}
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, s); err != nil {
+			return stmts, err
+		}
+	}
+
+	if foreign_keys {
+		if err = foreign_key_check(ctx, tx, "pragma foreign_key_check"); err != nil {
+			return stmts, err
+		}
+	}
+
+	return stmts, tx.Commit()
+}
+
+// foreign_key_check runs the pragma and reports the tables that have
+// violations with ErrForeignKeyViolations.
+func foreign_key_check(ctx context.Context, tx *sql.Tx, pragma string) error {
+	violations := ErrForeignKeyViolations{}
+	err := query(ctx, tx, pragma, nil, func(row *sql.Rows) error {
+		var table string
+		var rowid sql.NullInt64
+		var parent string
+		var fkid int
+		if err := row.Scan(&table, &rowid, &parent, &fkid); err != nil {
+			return err
+		}
+		for _, t := range violations {
+			if t == table {
+				return nil
+			}
+		}
+		violations = append(violations, table)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
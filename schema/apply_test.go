@@ -0,0 +1,112 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+func ExampleApply() {
+
+	model := `
+tables:
+  - table: users
+    columns:
+      - {name: id, type: int64}
+      - {name: name, type: text}
+      - {name: email, type: text, nullable: true}
+    pk: [id]
+    indices:
+      - {name: users_email, unique: true, columns: [email]}
+  - table: groups
+    columns:
+      - {name: id, type: int64}
+      - {name: title, type: text, default: "''"}
+    pk: [id]
+`
+	want := &Database{}
+	if err := yaml.Unmarshal([]byte(model), want); err != nil {
+		fmt.Println(err)
+	}
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table users (id int64 not null primary key, name text not null);
+		create table legacy (x);
+	`)
+
+	stmts, err := Apply(db, want, &ApplyOptions{DryRun: true})
+	for _, s := range stmts {
+		fmt.Println(s)
+	}
+	fmt.Println(err)
+
+	Apply(db, want, nil)
+	have, _ := Scan(db)
+	fmt.Println(have.HasTable("groups"), have.HasTable("legacy"), Diff(have, want))
+
+	// Output:
+	// create table "groups" (
+	//     id     int64  not null,
+	//     title  text   not null default "",
+	//     primary key (id)
+	// );
+	// alter table users add column email text;
+	// create unique index users_email on users(email);
+	// <nil>
+	// true true - table legacy
+}
+
+func ExampleApply_rebuild() {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		pragma foreign_keys = on;
+		create table t (id integer primary key, v text);
+		create table log (id int);
+		create view tv as select id, v from t;
+		create view tv2 as select v from tv;
+		create trigger t_ins after insert on t begin insert into log values (new.id); end;
+		insert into t values (1, 'a'), (2, null);
+	`)
+
+	want, _ := Scan(db)
+	t, _ := want.FindTable("t")
+	v, _ := t.FindColumn("v")
+	v.Nullable = false
+	v.Default = RawLiteral("'x'")
+
+	stmts, err := Apply(db, want, &ApplyOptions{Rebuild: true})
+	for _, s := range stmts {
+		fmt.Println(s)
+	}
+	fmt.Println(err)
+
+	db.Exec("insert into t values (3, 'c')")
+	var n int
+	db.QueryRow("select count(*) from tv2 where v is not null").Scan(&n)
+	fmt.Println(n)
+	db.QueryRow("select count(*) from log").Scan(&n)
+	fmt.Println(n)
+
+	// Output:
+	// drop trigger t_ins;
+	// drop view tv2;
+	// drop view tv;
+	// create table new_t (
+	//     id  INTEGER  primary key,
+	//     v   TEXT     not null default 'x'
+	// );
+	// insert into new_t (id, v) select id, coalesce(v, 'x') from t;
+	// drop table t;
+	// alter table new_t rename to t;
+	// pragma foreign_key_check(t);
+	// CREATE VIEW tv as select id, v from t;
+	// CREATE VIEW tv2 as select v from tv;
+	// CREATE TRIGGER t_ins after insert on t begin insert into log values (new.id); end;
+	// <nil>
+	// 3
+	// 3
+}
@@ -3,8 +3,8 @@ package schema
 import (
 	"encoding/json"
 	"strconv"
-	"strings"
 
+	"github.com/adnsv/go-db3/internal/sqltext"
 	"gopkg.in/yaml.v3"
 )
 
@@ -67,33 +67,31 @@ func (v NULL) MarshalJSON() ([]byte, error) { return []byte("null"), nil }
 func (v NULL) MarshalYAML() (any, error)    { return nil, nil }
 
 func parseLiteral(s string) Literal {
-	switch strings.ToLower(s) {
-	case "null":
+	l := sqltext.ParseLiteral(s)
+	switch l.Kind {
+	case sqltext.NullLiteral:
 		return NULL{}
-	case "current_time":
+	case sqltext.CurrentTimeLiteral:
 		return CurrentTime{}
-	case "current_date":
+	case sqltext.CurrentDateLiteral:
 		return CurrentDate{}
-	case "current_timestamp":
+	case sqltext.CurrentTimestampLiteral:
 		return CurrentTimestamp{}
-	case "true":
+	case sqltext.TrueLiteral:
 		return LiteralBoolean(true)
-	case "false":
+	case sqltext.FalseLiteral:
 		return LiteralBoolean(false)
+	case sqltext.IntLiteral:
+		return LiteralInt(l.Int)
 	default:
-		if v, err := strconv.ParseInt(s, 10, 64); err == nil {
-			return LiteralInt(v)
-		} else {
-			return RawLiteral(requote(s))
-		}
+		return RawLiteral(l.Text)
 	}
 }
 
-func requote(s string) string {
-	n := len(s)
-	if n >= 2 && s[0] == '\'' && s[n-1] == '\'' {
-		return `"` + s[1:n-1] + `"`
-	} else {
-		return s
-	}
+func parseForeignKeyAction(s string) ForeignKeyAction {
+	return ForeignKeyAction(sqltext.ForeignKeyAction(s))
+}
+
+func parseConflictAction(s string) ConflictAction {
+	return ConflictAction(sqltext.ConflictAction(s))
 }
@@ -0,0 +1,709 @@
+// Package ddl loads database schemas from sql scripts without a live
+// database connection. The result mirrors what schema.Scan reports for a
+// database created by the same script.
+package ddl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/adnsv/go-db3/internal/sqltext"
+	"github.com/adnsv/go-db3/schema"
+)
+
+// Parse produces the schema described by the statements of the sql script,
+// see Exec.
+func Parse(src string) (*schema.Database, error) {
+	db := &schema.Database{}
+	if err := Exec(db, src); err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Exec applies the statements of the sql script to the schema:
+//
+//   - 'create table', 'create index', 'create view' and 'create trigger'
+//     add objects to the schema
+//   - 'drop' statements remove them
+//   - 'alter table' renames tables and columns, adds and drops columns
+//
+// Temporary objects, virtual tables and all other statements are skipped.
+// Renames are not propagated into the sql text of views and triggers.
+func Exec(db *schema.Database, src string) error {
+	p, err := sqltext.NewParser(src)
+	if err != nil {
+		return err
+	}
+	for {
+		for p.AcceptPunct(";") {
+		}
+		t := p.Peek()
+		if t.Kind == sqltext.EOF {
+			return nil
+		}
+		switch {
+		case t.Is("create"):
+			err = exec_create(db, p)
+		case t.Is("drop"):
+			err = exec_drop(db, p)
+		case t.Is("alter"):
+			err = exec_alter(db, p)
+		default:
+			p.SkipStatement()
+		}
+		if err == nil && !p.Peek().IsPunct(";") && p.Peek().Kind != sqltext.EOF {
+			err = p.Errorf("expected ';'")
+		}
+		if err != nil {
+			return fmt.Errorf("statement at offset %d: %w", t.Pos, err)
+		}
+	}
+}
+
+func exec_create(db *schema.Database, p *sqltext.Parser) error {
+	// the kind of the object follows optional 'temp' and 'unique'
+	i := 1
+	temporary := p.PeekAt(i).Is("temp") || p.PeekAt(i).Is("temporary")
+	if temporary {
+		i++
+	}
+	unique := p.PeekAt(i).Is("unique")
+	if unique {
+		i++
+	}
+	kind := p.PeekAt(i)
+
+	// sqlite stores the statement text from the object name on, with the
+	// normalized prefix
+	name_at := i + 1
+	if p.PeekAt(name_at).Is("if") {
+		name_at += 3
+	}
+	start := p.Pos() + name_at
+	stored_sql := func(prefix string) string {
+		return prefix + " " + p.Text(start, p.Pos())
+	}
+
+	switch {
+	case kind.Is("table") && !unique:
+		ct, err := p.CreateTable()
+		if err != nil || ct.Temporary {
+			return err
+		}
+		return add_table(db, ct)
+
+	case kind.Is("index"):
+		ci, err := p.CreateIndex()
+		if err != nil || temporary {
+			return err
+		}
+		return add_index(db, ci)
+
+	case kind.Is("view") && !unique:
+		cv, err := p.CreateView()
+		if err != nil || cv.Temporary {
+			return err
+		}
+		if err := reserved(cv.Name); err != nil {
+			return err
+		}
+		if exists(db, cv.Name) {
+			return if_not_exists(cv.IfNotExists, "view", cv.Name)
+		}
+		db.Views = append(db.Views, &schema.View{Name: cv.Name, SQL: stored_sql("CREATE VIEW")})
+		return nil
+
+	case kind.Is("trigger") && !unique:
+		tr, err := p.CreateTrigger()
+		if err != nil || tr.Temporary {
+			return err
+		}
+		return add_trigger(db, tr, stored_sql("CREATE TRIGGER"))
+
+	case kind.Is("virtual") && !unique && !temporary:
+		p.SkipStatement()
+		return nil
+
+	default:
+		return p.Errorf("expected table, index, view or trigger")
+	}
+}
+
+func add_table(db *schema.Database, ct *sqltext.CreateTable) error {
+	if err := reserved(ct.Name); err != nil {
+		return err
+	}
+	if exists(db, ct.Name) {
+		return if_not_exists(ct.IfNotExists, "table", ct.Name)
+	}
+	if len(ct.Columns) == 0 {
+		return fmt.Errorf("table %s: 'create table as select' is not supported", ct.Name)
+	}
+	t := &schema.Table{
+		Name:         ct.Name,
+		WithoutRowID: ct.WithoutRowID,
+		Strict:       ct.Strict,
+	}
+	for _, cd := range ct.Columns {
+		t.Columns = append(t.Columns, new_column(t, cd))
+	}
+	for _, c := range ct.Constraints {
+		switch c.Kind {
+		case sqltext.PrimaryKey:
+			t.PK = column_names(c.Columns)
+			t.PKOnConflict = schema.ConflictAction(sqltext.ConflictAction(c.OnConflict))
+		case sqltext.Unique:
+			t.Uniques = append(t.Uniques, &schema.UniqueConstraint{
+				Name:       c.Name,
+				Columns:    column_names(c.Columns),
+				OnConflict: schema.ConflictAction(sqltext.ConflictAction(c.OnConflict)),
+			})
+		case sqltext.Check:
+			t.Checks = append(t.Checks, &schema.CheckConstraint{Name: c.Name, Expr: c.Expr})
+		case sqltext.ForeignKey:
+			t.ForeignKeys = append(t.ForeignKeys, new_foreign_key(column_names(c.Columns), c.References))
+		}
+	}
+
+	// primary key columns of tables without rowid are implicitly not null
+	if t.WithoutRowID {
+		for _, n := range t.PK {
+			if c, ok := find_column(t, n); ok {
+				c.Nullable = false
+			}
+		}
+	}
+	db.Tables = append(db.Tables, t)
+	return nil
+}
+
+// new_column converts the column definition, column-level primary key,
+// unique and foreign key constraints are added to the table.
+func new_column(t *schema.Table, cd *sqltext.ColumnDef) *schema.Column {
+	c := &schema.Column{
+		Name:     cd.Name,
+		Type:     column_type(cd.Type),
+		Nullable: true,
+	}
+	checks := []string{}
+	for _, k := range cd.Constraints {
+		switch k.Kind {
+		case sqltext.NotNull:
+			c.Nullable = false
+			c.OnConflict = schema.ConflictAction(sqltext.ConflictAction(k.OnConflict))
+		case sqltext.Default:
+			c.Default = default_value(k.Expr)
+		case sqltext.Collate:
+			c.Collate = k.Collation
+		case sqltext.Check:
+			checks = append(checks, k.Expr)
+		case sqltext.Generated:
+			c.Generated = k.Expr
+			c.Stored = k.Stored
+		case sqltext.PrimaryKey:
+			t.PK = []string{cd.Name}
+			t.PKOnConflict = schema.ConflictAction(sqltext.ConflictAction(k.OnConflict))
+			t.AutoIncrement = k.AutoIncrement
+		case sqltext.Unique:
+			t.Uniques = append(t.Uniques, &schema.UniqueConstraint{
+				Name:       k.Name,
+				Columns:    []string{cd.Name},
+				OnConflict: schema.ConflictAction(sqltext.ConflictAction(k.OnConflict)),
+			})
+		case sqltext.ForeignKey:
+			t.ForeignKeys = append(t.ForeignKeys, new_foreign_key([]string{cd.Name}, k.References))
+		}
+	}
+	// multiple checks are combined the same way Scan does it
+	if len(checks) == 1 {
+		c.Check = checks[0]
+	} else if len(checks) > 1 {
+		c.Check = "(" + strings.Join(checks, ") and (") + ")"
+	}
+	return c
+}
+
+// default_value converts the sql text of a default value into the literal
+// that Scan reports for it.
+func default_value(s string) schema.Literal {
+	l := sqltext.ParseLiteral(s)
+	switch l.Kind {
+	case sqltext.NullLiteral:
+		return schema.NULL{}
+	case sqltext.CurrentTimeLiteral:
+		return schema.CurrentTime{}
+	case sqltext.CurrentDateLiteral:
+		return schema.CurrentDate{}
+	case sqltext.CurrentTimestampLiteral:
+		return schema.CurrentTimestamp{}
+	case sqltext.TrueLiteral:
+		return schema.LiteralBoolean(true)
+	case sqltext.FalseLiteral:
+		return schema.LiteralBoolean(false)
+	case sqltext.IntLiteral:
+		return schema.LiteralInt(l.Int)
+	default:
+		return schema.RawLiteral(l.Text)
+	}
+}
+
+// column_type reproduces the type reported by sqlite: declared types are kept
+// as written, except for the standard types that are reported in upper case.
+func column_type(declared string) schema.ColumnType {
+	switch u := strings.ToUpper(declared); u {
+	case "INT", "INTEGER", "REAL", "TEXT", "BLOB", "ANY":
+		return schema.ColumnType(u)
+	}
+	return schema.ColumnType(declared)
+}
+
+func new_foreign_key(columns []string, r *sqltext.References) *schema.ForeignKey {
+	return &schema.ForeignKey{
+		Columns:       columns,
+		ParentTable:   r.Table,
+		ParentColumns: r.Columns,
+		OnUpdate:      schema.ForeignKeyAction(sqltext.ForeignKeyAction(r.OnUpdate)),
+		OnDelete:      schema.ForeignKeyAction(sqltext.ForeignKeyAction(r.OnDelete)),
+		Deferrable:    schema.Deferrable(r.Deferrable),
+	}
+}
+
+func column_names(cc []*sqltext.IndexedColumn) []string {
+	names := make([]string, len(cc))
+	for i, c := range cc {
+		names[i] = c.Name
+	}
+	return names
+}
+
+func add_index(db *schema.Database, ci *sqltext.CreateIndex) error {
+	t, ok := find_table(db, ci.Table)
+	if !ok {
+		return fmt.Errorf("index %s: no such table: %s", ci.Name, ci.Table)
+	}
+	if err := reserved(ci.Name); err != nil {
+		return err
+	}
+	if exists(db, ci.Name) {
+		return if_not_exists(ci.IfNotExists, "index", ci.Name)
+	}
+	idx := &schema.Index{
+		Name:    ci.Name,
+		Unique:  ci.Unique,
+		Columns: []schema.IndexColumn{},
+		Where:   ci.Where,
+	}
+	for _, c := range ci.Columns {
+		ic := schema.IndexColumn{Name: c.Name}
+		if c.Name == "" {
+			if err := ic.UnmarshalText([]byte(c.Expr)); err != nil {
+				return fmt.Errorf("index %s: %w", ci.Name, err)
+			}
+		} else if tc, ok := find_column(t, c.Name); ok {
+			// sqlite reports the collation of the table column for index
+			// columns declared without one
+			ic.Name = tc.Name
+			ic.Collation = tc.Collate
+		}
+		if c.Collation != "" {
+			ic.Collation = c.Collation
+		}
+		if strings.EqualFold(ic.Collation, "binary") {
+			ic.Collation = ""
+		}
+		ic.Desc = c.Desc
+		idx.Columns = append(idx.Columns, ic)
+	}
+	t.Indices = append(t.Indices, idx)
+	return nil
+}
+
+func add_trigger(db *schema.Database, tr *sqltext.CreateTrigger, trigger_sql string) error {
+	if err := reserved(tr.Name); err != nil {
+		return err
+	}
+	if _, ok := find_trigger(db, tr.Name); ok {
+		return if_not_exists(tr.IfNotExists, "trigger", tr.Name)
+	}
+	table := tr.Table
+	if t, ok := find_table(db, tr.Table); ok {
+		table = t.Name
+	} else if v, ok := find_view(db, tr.Table); ok {
+		table = v.Name
+	} else {
+		return fmt.Errorf("trigger %s: no such table: %s", tr.Name, tr.Table)
+	}
+	timing := schema.TriggerTiming(tr.Timing)
+	if timing == "" {
+		timing = schema.Before
+	}
+	db.Triggers = append(db.Triggers, &schema.Trigger{
+		Name:    tr.Name,
+		Table:   table,
+		Timing:  timing,
+		Event:   schema.TriggerEvent(tr.Event),
+		Columns: tr.Columns,
+		SQL:     trigger_sql,
+	})
+	return nil
+}
+
+func exec_drop(db *schema.Database, p *sqltext.Parser) error {
+	p.Next()
+	kind := p.Next()
+	if_exists := p.Accept("if", "exists")
+	_, name, err := p.QualifiedName()
+	if err != nil {
+		return err
+	}
+
+	found := false
+	switch {
+	case kind.Is("table"):
+		if t, ok := find_table(db, name); ok {
+			found = true
+			db.Tables = without(db.Tables, t)
+			// triggers are dropped along with the table
+			for _, tr := range append([]*schema.Trigger{}, db.Triggers...) {
+				if strings.EqualFold(tr.Table, t.Name) {
+					db.Triggers = without(db.Triggers, tr)
+				}
+			}
+		}
+	case kind.Is("index"):
+		for _, t := range db.Tables {
+			if idx, ok := find_index(t, name); ok {
+				found = true
+				t.Indices = without(t.Indices, idx)
+				break
+			}
+		}
+	case kind.Is("view"):
+		if v, ok := find_view(db, name); ok {
+			found = true
+			db.Views = without(db.Views, v)
+		}
+	case kind.Is("trigger"):
+		if tr, ok := find_trigger(db, name); ok {
+			found = true
+			db.Triggers = without(db.Triggers, tr)
+		}
+	default:
+		return fmt.Errorf("expected table, index, view or trigger, got '%s'", kind.Text)
+	}
+	if !found && !if_exists {
+		return fmt.Errorf("no such %s: %s", strings.ToLower(kind.Text), name)
+	}
+	return nil
+}
+
+func exec_alter(db *schema.Database, p *sqltext.Parser) error {
+	if err := p.Expect("alter", "table"); err != nil {
+		return err
+	}
+	_, name, err := p.QualifiedName()
+	if err != nil {
+		return err
+	}
+	t, ok := find_table(db, name)
+	if !ok {
+		return fmt.Errorf("no such table: %s", name)
+	}
+
+	switch {
+	case p.Accept("rename", "to"):
+		new_name, err := p.Name()
+		if err != nil {
+			return err
+		}
+		if exists(db, new_name) && !strings.EqualFold(new_name, t.Name) {
+			return fmt.Errorf("there is already another table or index with this name: %s", new_name)
+		}
+		if err := reserved(new_name); err != nil {
+			return err
+		}
+		rename_table(db, t, new_name)
+		return nil
+
+	case p.Accept("rename"):
+		p.Accept("column")
+		old_name, err := p.Name()
+		if err != nil {
+			return err
+		}
+		if err := p.Expect("to"); err != nil {
+			return err
+		}
+		new_name, err := p.Name()
+		if err != nil {
+			return err
+		}
+		c, ok := find_column(t, old_name)
+		if !ok {
+			return fmt.Errorf("no such column: %s", old_name)
+		}
+		if _, dup := find_column(t, new_name); dup && !strings.EqualFold(old_name, new_name) {
+			return fmt.Errorf("duplicate column name: %s", new_name)
+		}
+		rename_column(db, t, c.Name, new_name)
+		return nil
+
+	case p.Accept("add"):
+		p.Accept("column")
+		cd, err := p.ColumnDef()
+		if err != nil {
+			return err
+		}
+		if _, dup := find_column(t, cd.Name); dup {
+			return fmt.Errorf("duplicate column name: %s", cd.Name)
+		}
+		if err := can_add_column(cd); err != nil {
+			return err
+		}
+		t.Columns = append(t.Columns, new_column(t, cd))
+		return nil
+
+	case p.Accept("drop"):
+		p.Accept("column")
+		column, err := p.Name()
+		if err != nil {
+			return err
+		}
+		c, ok := find_column(t, column)
+		if !ok {
+			return fmt.Errorf("no such column: %s", column)
+		}
+		if is_referenced(t, c.Name) {
+			return fmt.Errorf("cannot drop column %s: it is used by a key, index, constraint or generated column", c.Name)
+		}
+		t.Columns = without(t.Columns, c)
+		return nil
+
+	default:
+		return p.Errorf("expected rename, add or drop")
+	}
+}
+
+// rename_table renames the table along with references to it from foreign
+// keys and triggers.
+func rename_table(db *schema.Database, t *schema.Table, name string) {
+	for _, other := range db.Tables {
+		for _, fk := range other.ForeignKeys {
+			if strings.EqualFold(fk.ParentTable, t.Name) {
+				fk.ParentTable = name
+			}
+		}
+	}
+	for _, tr := range db.Triggers {
+		if strings.EqualFold(tr.Table, t.Name) {
+			tr.Table = name
+		}
+	}
+	t.Name = name
+}
+
+// rename_column renames the column along with references to it from keys,
+// constraints and indices.
+func rename_column(db *schema.Database, t *schema.Table, from, to string) {
+	rename := func(names []string) {
+		for i, n := range names {
+			if strings.EqualFold(n, from) {
+				names[i] = to
+			}
+		}
+	}
+	c, _ := find_column(t, from)
+	c.Name = to
+	rename(t.PK)
+	for _, u := range t.Uniques {
+		rename(u.Columns)
+	}
+	for _, fk := range t.ForeignKeys {
+		rename(fk.Columns)
+	}
+	for _, idx := range t.Indices {
+		for i := range idx.Columns {
+			if strings.EqualFold(idx.Columns[i].Name, from) {
+				idx.Columns[i].Name = to
+			}
+		}
+	}
+	for _, other := range db.Tables {
+		for _, fk := range other.ForeignKeys {
+			if strings.EqualFold(fk.ParentTable, t.Name) {
+				rename(fk.ParentColumns)
+			}
+		}
+	}
+}
+
+// is_referenced checks if the column is used by the primary key, unique
+// constraints, foreign keys, indices, check constraints of the table or of
+// other columns, or generated column expressions, sqlite refuses to drop
+// such columns.
+func is_referenced(t *schema.Table, column string) bool {
+	contains := func(names []string) bool {
+		for _, n := range names {
+			if strings.EqualFold(n, column) {
+				return true
+			}
+		}
+		return false
+	}
+	if contains(t.PK) {
+		return true
+	}
+	for _, u := range t.Uniques {
+		if contains(u.Columns) {
+			return true
+		}
+	}
+	for _, fk := range t.ForeignKeys {
+		if contains(fk.Columns) {
+			return true
+		}
+	}
+	for _, idx := range t.Indices {
+		for _, c := range idx.Columns {
+			if strings.EqualFold(c.Name, column) || c.IsExpression() && mentions(c.Name, column) {
+				return true
+			}
+		}
+		if mentions(idx.Where, column) {
+			return true
+		}
+	}
+	for _, c := range t.Checks {
+		if mentions(c.Expr, column) {
+			return true
+		}
+	}
+	for _, c := range t.Columns {
+		if strings.EqualFold(c.Name, column) {
+			// the own check constraint is dropped along with the column
+			continue
+		}
+		if mentions(c.Check, column) || mentions(c.Generated, column) {
+			return true
+		}
+	}
+	return false
+}
+
+// mentions checks if the expression refers to the name.
+func mentions(expr, name string) bool {
+	tt, err := sqltext.Tokenize(expr)
+	if err != nil {
+		return false
+	}
+	for _, t := range tt {
+		if (t.Kind == sqltext.Ident || t.Kind == sqltext.Quoted) && strings.EqualFold(t.Value(), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// reserved rejects the names that sqlite keeps for its own objects, such as
+// sqlite_sequence.
+// can_add_column applies the restrictions of 'alter table add column' with
+// the errors sqlite reports.
+func can_add_column(cd *sqltext.ColumnDef) error {
+	not_null, has_default, generated := false, false, false
+	for _, k := range cd.Constraints {
+		switch k.Kind {
+		case sqltext.PrimaryKey:
+			return fmt.Errorf("Cannot add a PRIMARY KEY column")
+		case sqltext.Unique:
+			return fmt.Errorf("Cannot add a UNIQUE column")
+		case sqltext.NotNull:
+			not_null = true
+		case sqltext.Default:
+			has_default = sqltext.ParseLiteral(k.Expr).Kind != sqltext.NullLiteral
+		case sqltext.Generated:
+			if k.Stored {
+				return fmt.Errorf("cannot add a STORED column")
+			}
+			generated = true
+		}
+	}
+	if not_null && !has_default && !generated {
+		return fmt.Errorf("Cannot add a NOT NULL column with default value NULL")
+	}
+	return nil
+}
+
+func reserved(name string) error {
+	if strings.HasPrefix(strings.ToLower(name), "sqlite_") {
+		return fmt.Errorf("object name reserved for internal use: %s", name)
+	}
+	return nil
+}
+
+func if_not_exists(allowed bool, kind, name string) error {
+	if allowed {
+		return nil
+	}
+	return fmt.Errorf("%s %s already exists", kind, name)
+}
+
+// exists checks if the name is taken by a table, index or view, these share
+// the namespace in sqlite.
+func exists(db *schema.Database, name string) bool {
+	if _, ok := find_table(db, name); ok {
+		return true
+	}
+	if _, ok := find_view(db, name); ok {
+		return true
+	}
+	for _, t := range db.Tables {
+		if _, ok := find_index(t, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// sqlite names are case-insensitive, the lookups below compare them
+// accordingly.
+
+func find_table(db *schema.Database, name string) (*schema.Table, bool) {
+	return find(db.Tables, func(t *schema.Table) string { return t.Name }, name)
+}
+
+func find_view(db *schema.Database, name string) (*schema.View, bool) {
+	return find(db.Views, func(v *schema.View) string { return v.Name }, name)
+}
+
+func find_trigger(db *schema.Database, name string) (*schema.Trigger, bool) {
+	return find(db.Triggers, func(t *schema.Trigger) string { return t.Name }, name)
+}
+
+func find_column(t *schema.Table, name string) (*schema.Column, bool) {
+	return find(t.Columns, func(c *schema.Column) string { return c.Name }, name)
+}
+
+func find_index(t *schema.Table, name string) (*schema.Index, bool) {
+	return find(t.Indices, func(idx *schema.Index) string { return idx.Name }, name)
+}
+
+func find[T any](items []T, name_of func(T) string, name string) (T, bool) {
+	for _, v := range items {
+		if strings.EqualFold(name_of(v), name) {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func without[T comparable](items []T, item T) []T {
+	r := make([]T, 0, len(items))
+	for _, v := range items {
+		if v != item {
+			r = append(r, v)
+		}
+	}
+	return r
+}
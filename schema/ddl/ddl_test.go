@@ -0,0 +1,271 @@
+package ddl
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/adnsv/go-db3/schema"
+	_ "github.com/mattn/go-sqlite3"
+	"gopkg.in/yaml.v3"
+)
+
+const script = `
+	create table authors (
+		id       integer primary key autoincrement,
+		name     text not null on conflict replace collate nocase,
+		email    text unique check (email like '%@%') check (length(email) < 100),
+		born     date default current_date,
+		rating   real default -1,
+		weight   numeric(10, 2),
+		initials text generated always as (substr(name, 1, 1)) virtual,
+		slug     text as (lower(name)) stored
+	);
+	create table books (
+		author   int not null references authors(id) on delete cascade,
+		isbn     text not null,
+		title    text default 'untitled',
+		price    real default (0.5 * 2),
+		editor   integer,
+		primary key (author, isbn) on conflict ignore,
+		constraint uq_title unique (author, title),
+		constraint positive check (price >= 0),
+		foreign key (editor) references authors deferrable initially deferred
+	) without rowid, strict;
+	create index if not exists books_title on books(title collate nocase desc, isbn);
+	create unique index books_lower on books(lower(title), author) where price > 0;
+	create view if not exists expensive as
+		select title from books where price > 10;
+	create view authors_expensive (name, title) as
+		select a.name, e.title from authors a, books b, expensive e
+		where a.id = b.author and b.title = e.title;
+	create trigger books_touch after update of price, title on books
+	begin
+		update authors set rating = case when new.price > 10 then 1 else 0 end
+		where id = new.author;
+	end;
+	create trigger authors_guard instead of delete on authors_expensive begin select 1; end;
+	create temp table scratch (x);
+	pragma foreign_keys = on;
+`
+
+func ExampleParse() {
+	db, err := Parse(`
+		create table t (
+			id   integer primary key,
+			name text not null default '',
+			kind int check (kind in (1, 2)),
+			unique (name, kind) on conflict replace
+		);
+		create index t_kind on t(kind desc) where kind > 1;`)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+	y, _ := yaml.Marshal(db.Tables)
+	fmt.Print(string(y))
+
+	// Output:
+	// - table: t
+	//   columns:
+	//     - {name: id, type: INTEGER, nullable: true}
+	//     - {name: name, type: TEXT, default: '""'}
+	//     - {name: kind, type: INT, nullable: true, check: 'kind in (1, 2)'}
+	//   indices:
+	//     - {name: t_kind, columns: [kind desc], where: kind > 1}
+	//   pk: [id]
+	//   uniques:
+	//     - {columns: [name, kind], on_conflict: replace}
+}
+
+func ExampleParse_scan() {
+	parsed, err := Parse(script)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	conn, _ := sql.Open("sqlite3", ":memory:")
+	if _, err := conn.Exec(script); err != nil {
+		fmt.Print(err)
+		return
+	}
+	scanned, err := schema.Scan(conn)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	fmt.Println("scanned -> parsed:", schema.Diff(scanned, parsed).Empty())
+	fmt.Println("parsed -> scanned:", schema.Diff(parsed, scanned).Empty())
+	fmt.Println("views:", same_views(parsed.Views, scanned.Views))
+	fmt.Println("triggers:", reflect.DeepEqual(parsed.Triggers, scanned.Triggers))
+
+	// Output:
+	// scanned -> parsed: true
+	// parsed -> scanned: true
+	// views: true
+	// triggers: true
+}
+
+func ExampleParse_createStatements() {
+	parsed, err := Parse(script)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	b := bytes.Buffer{}
+	parsed.CreateStatements(&b)
+	reparsed, err := Parse(b.String())
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	fmt.Println("diff:", schema.Diff(parsed, reparsed).Empty(), schema.Diff(reparsed, parsed).Empty())
+	fmt.Println("views:", same_views(parsed.Views, reparsed.Views))
+	fmt.Println("triggers:", reflect.DeepEqual(parsed.Triggers, reparsed.Triggers))
+
+	// Output:
+	// diff: true true
+	// views: true
+	// triggers: true
+}
+
+func ExampleExec() {
+	db, _ := Parse(`
+		create table a (id integer primary key, x int, y int);
+		create table b (a_id int references a(id));
+		create index a_x on a(x);
+		create trigger a_ins after insert on a begin select 1; end;`)
+
+	err := Exec(db, `
+		alter table a rename column id to a_id;
+		alter table a rename to parent;
+		alter table parent add column z text not null default 'z';
+		alter table parent drop column y;
+		drop index a_x;
+		drop index if exists a_x;`)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+	y, _ := yaml.Marshal(db)
+	fmt.Print(string(y))
+
+	fmt.Println(Exec(db, "alter table parent drop column a_id"))
+	fmt.Println(Exec(db, "create table b (x)"))
+	fmt.Println(Exec(db, "create table sqlite_sequence (name, seq)"))
+
+	// Output:
+	// tables:
+	//     - table: parent
+	//       columns:
+	//         - {name: a_id, type: INTEGER, nullable: true}
+	//         - {name: x, type: INT, nullable: true}
+	//         - {name: z, type: TEXT, default: '"z"'}
+	//       pk: [a_id]
+	//     - table: b
+	//       columns:
+	//         - {name: a_id, type: INT, nullable: true}
+	//       foreign_keys:
+	//         - {columns: [a_id], parent_table: parent, parent_columns: [a_id]}
+	// triggers:
+	//     - trigger: a_ins
+	//       table: parent
+	//       timing: after
+	//       event: insert
+	//       sql: CREATE TRIGGER a_ins after insert on a begin select 1; end
+	// statement at offset 0: cannot drop column a_id: it is used by a key, index, constraint or generated column
+	// statement at offset 0: table b already exists
+	// statement at offset 0: object name reserved for internal use: sqlite_sequence
+}
+
+func ExampleExec_dropColumn() {
+	script := `
+		create table t (
+			a int, b int, c int check (c > a), d int as (b * 2),
+			e int, f int check (f > 0), g int, h text, i int,
+			check (i > 0)
+		);
+		create index t_e on t(e) where g > 0;
+		create index t_h on t(lower(h));`
+
+	conn, _ := sql.Open("sqlite3", ":memory:")
+	conn.SetMaxOpenConns(1)
+	conn.Exec(script)
+
+	for _, c := range []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"} {
+		db, _ := Parse(script)
+		err := Exec(db, "alter table t drop column "+c)
+		// sqlite drops the column within a savepoint that is rolled back
+		conn.Exec("savepoint s")
+		_, sqlite_err := conn.Exec("alter table t drop column " + c)
+		conn.Exec("rollback to s; release s")
+		fmt.Println(c, err == nil, sqlite_err == nil)
+	}
+
+	// Output:
+	// a false false
+	// b false false
+	// c true true
+	// d true true
+	// e false false
+	// f true true
+	// g false false
+	// h false false
+	// i false false
+}
+
+func ExampleExec_addColumn() {
+	script := `create table t (a int);`
+
+	conn, _ := sql.Open("sqlite3", ":memory:")
+	conn.SetMaxOpenConns(1)
+	conn.Exec(script + "insert into t values (1);")
+
+	for _, c := range []string{
+		"b int primary key",
+		"b int unique",
+		"b int not null",
+		"b int not null default null",
+		"b int not null default 0",
+		"b int as (a * 2) stored",
+		"b int as (a * 2) not null",
+	} {
+		db, _ := Parse(script)
+		err := Exec(db, "alter table t add column "+c)
+		conn.Exec("savepoint s")
+		_, sqlite_err := conn.Exec("alter table t add column " + c)
+		conn.Exec("rollback to s; release s")
+		fmt.Printf("%s: %v, %v\n", c, err, sqlite_err)
+	}
+
+	db, _ := Parse(script)
+	fmt.Println(Exec(db, "alter table t rename to sqlite_t"))
+
+	// Output:
+	// b int primary key: statement at offset 0: Cannot add a PRIMARY KEY column, Cannot add a PRIMARY KEY column
+	// b int unique: statement at offset 0: Cannot add a UNIQUE column, Cannot add a UNIQUE column
+	// b int not null: statement at offset 0: Cannot add a NOT NULL column with default value NULL, Cannot add a NOT NULL column with default value NULL
+	// b int not null default null: statement at offset 0: Cannot add a NOT NULL column with default value NULL, Cannot add a NOT NULL column with default value NULL
+	// b int not null default 0: <nil>, <nil>
+	// b int as (a * 2) stored: statement at offset 0: cannot add a STORED column, cannot add a STORED column
+	// b int as (a * 2) not null: <nil>, <nil>
+	// statement at offset 0: object name reserved for internal use: sqlite_t
+}
+
+// same_views compares views by name and sql, columns are only known to Scan.
+func same_views(a, b []*schema.View) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Name != b[i].Name || a[i].SQL != b[i].SQL {
+			return false
+		}
+	}
+	return true
+}
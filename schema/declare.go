@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/adnsv/go-db3/internal/sqltext"
 )
 
 type CreateFlag int
@@ -22,7 +24,7 @@ func (t *Table) CreateStatements(w io.Writer, flags ...CreateFlag) {
 	}
 
 	fmt.Fprintf(out, "create %stable %s (",
-		temporary, t.Name)
+		temporary, QuoteName(t.Name))
 
 	first := true
 
@@ -35,27 +37,23 @@ func (t *Table) CreateStatements(w io.Writer, flags ...CreateFlag) {
 		out.WriteString("\n    ")
 	}
 
+	// rowid aliases are declared with a column-level primary key, it is the
+	// only place where autoincrement is allowed
+	alias := t.RowIDAlias()
+
 	grid := table_grid{}
 	col_widths := []int{}
 	for _, f := range t.Columns {
-		row := []string{f.Name}
-
-		if s := string(f.Type); s != "" {
-			row = append(row, s)
-		}
-
-		attrs := []string{}
-		if !f.Nullable {
-			attrs = append(attrs, "not null")
-		}
-		if f.Default != nil {
-			attrs = append(attrs, "default "+f.Default.SQLLiteral())
-		}
-		if len(attrs) > 0 {
-			if len(row) < 2 {
+		row := f.definition_cells()
+		if f == alias {
+			pk := "primary key" + conflict_clause(t.PKOnConflict)
+			if t.AutoIncrement {
+				pk += " autoincrement"
+			}
+			for len(row) < 3 {
 				row = append(row, "")
 			}
-			row = append(row, strings.Join(attrs, " "))
+			row[2] = strings.TrimSpace(row[2] + " " + pk)
 		}
 		measure_cells(row, &col_widths)
 		grid = append(grid, row)
@@ -87,9 +85,27 @@ func (t *Table) CreateStatements(w io.Writer, flags ...CreateFlag) {
 		}
 	}
 
-	if len(t.PK) > 0 {
+	if len(t.PK) > 0 && alias == nil {
+		indented()
+		out.WriteString("primary key (" + name_list(t.PK) + ")")
+		out.WriteString(conflict_clause(t.PKOnConflict))
+	}
+
+	for _, u := range t.Uniques {
 		indented()
-		out.WriteString("primary key (" + strings.Join(t.PK, ",") + ")")
+		out.WriteString(constraint_name(u.Name) + "unique (" + name_list(u.Columns) + ")")
+		out.WriteString(conflict_clause(u.OnConflict))
+	}
+
+	for _, c := range t.Checks {
+		indented()
+		out.WriteString(constraint_name(c.Name) + "check (" + c.Expr + ")")
+	}
+
+	for _, fk := range t.ForeignKeys {
+		indented()
+		out.WriteString("foreign key (" + name_list(fk.Columns) + ") ")
+		out.WriteString(fk.ReferencesClause())
 	}
 
 	out.WriteString("\n)")
@@ -113,17 +129,215 @@ func (t *Table) CreateStatements(w io.Writer, flags ...CreateFlag) {
 	w.Write(out.Bytes())
 }
 
-func (t *Table) CreateIndexStatement(idx *Index) string {
-	n, u := idx.Name, ""
-	if len(n) == 0 {
-		// auto-generate
-		n = t.Name + "_" + strings.Join(idx.Columns, "_") + "_index"
+// CreateStatements writes the statements that create all tables, views and
+// triggers of the database. Views are written after the tables, ordered so
+// that each view follows the views it selects from, triggers are written last.
+// The flags apply to tables only.
+func (db *Database) CreateStatements(w io.Writer, flags ...CreateFlag) {
+	for _, t := range db.Tables {
+		t.CreateStatements(w, flags...)
+	}
+	for _, v := range ordered_views(db.Views) {
+		io.WriteString(w, statement(v.SQL))
+	}
+	for _, t := range db.Triggers {
+		io.WriteString(w, statement(t.SQL))
+	}
+}
+
+// statement terminates the sql text with a semicolon and a new line.
+func statement(s string) string {
+	return strings.TrimSuffix(strings.TrimSpace(s), ";") + ";\n"
+}
+
+// ordered_views sorts views so that each view follows the views it refers
+// to, otherwise keeping the original order.
+func ordered_views(views []*View) []*View {
+	by_name := map[string]*View{}
+	for _, v := range views {
+		by_name[strings.ToLower(v.Name)] = v
 	}
+
+	ordered := make([]*View, 0, len(views))
+	visited := map[*View]bool{}
+	var visit func(v *View)
+	visit = func(v *View) {
+		if visited[v] {
+			return
+		}
+		visited[v] = true
+		for _, n := range referenced_names(v.SQL) {
+			if dep, ok := by_name[n]; ok && dep != v {
+				visit(dep)
+			}
+		}
+		ordered = append(ordered, v)
+	}
+	for _, v := range views {
+		visit(v)
+	}
+	return ordered
+}
+
+// referenced_names enlists lowercased identifiers that appear in the sql text.
+func referenced_names(s string) []string {
+	tt, err := sqltext.Tokenize(s)
+	if err != nil {
+		return nil
+	}
+	names := []string{}
+	for _, t := range tt {
+		if t.Kind == sqltext.Ident || t.Kind == sqltext.Quoted {
+			names = append(names, strings.ToLower(t.Value()))
+		}
+	}
+	return names
+}
+
+func (t *Table) CreateIndexStatement(idx *Index) string {
+	u := ""
 	if idx.Unique {
 		u = "unique "
 	}
-	return fmt.Sprintf("create %sindex %s on %s(%s);",
-		u, n, t.Name, strings.Join(idx.Columns, ","))
+	where := ""
+	if idx.Where != "" {
+		where = " where " + idx.Where
+	}
+	return fmt.Sprintf("create %sindex %s on %s(%s)%s;",
+		u, QuoteName(t.IndexName(idx)), QuoteName(t.Name), index_columns(idx), where)
+}
+
+// IndexName returns the name of the index, auto-generating it from the table
+// and column names when the index is declared without one. Expressions are
+// named by their position, e.g. "expr1".
+func (t *Table) IndexName(idx *Index) string {
+	if len(idx.Name) > 0 {
+		return idx.Name
+	}
+	names := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		if c.IsExpression() {
+			names[i] = fmt.Sprintf("expr%d", i+1)
+		} else {
+			names[i] = c.Name
+		}
+	}
+	return t.Name + "_" + strings.Join(names, "_") + "_index"
+}
+
+// index_columns produces the comma-separated index column list.
+func index_columns(idx *Index) string {
+	cc := make([]string, len(idx.Columns))
+	for i, c := range idx.Columns {
+		cc[i] = c.String()
+	}
+	return strings.Join(cc, ",")
+}
+
+// definition_cells produces column definition split into name, type, and
+// constraints cells; the type cell is omitted for untyped columns without
+// constraints.
+func (c *Column) definition_cells() []string {
+	row := []string{QuoteName(c.Name)}
+
+	if s := string(c.Type); s != "" {
+		row = append(row, s)
+	}
+
+	attrs := []string{}
+	if !c.Nullable {
+		attrs = append(attrs, "not null"+conflict_clause(c.OnConflict))
+	}
+	if c.Default != nil {
+		attrs = append(attrs, "default "+c.Default.SQLLiteral())
+	}
+	if c.Generated != "" {
+		mode := "virtual"
+		if c.Stored {
+			mode = "stored"
+		}
+		attrs = append(attrs, "generated always as ("+c.Generated+") "+mode)
+	}
+	if c.Collate != "" {
+		attrs = append(attrs, "collate "+QuoteName(c.Collate))
+	}
+	if c.Check != "" {
+		attrs = append(attrs, "check ("+c.Check+")")
+	}
+	if len(attrs) > 0 {
+		if len(row) < 2 {
+			row = append(row, "")
+		}
+		row = append(row, strings.Join(attrs, " "))
+	}
+	return row
+}
+
+// definition produces column definition as used in 'create table' and 'alter
+// table add column' statements.
+func (c *Column) definition() string {
+	s := ""
+	for _, cell := range c.definition_cells() {
+		if cell == "" {
+			continue
+		}
+		if s != "" {
+			s += " "
+		}
+		s += cell
+	}
+	return s
+}
+
+// QuoteName produces the identifier for use in sql text, the identifier is
+// enclosed in double quotes only when it is a keyword or contains characters
+// other than ASCII letters, digits and underscores.
+func QuoteName(name string) string {
+	return sqltext.QuoteName(name)
+}
+
+// name_list produces the comma-separated list of quoted names.
+func name_list(names []string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = QuoteName(n)
+	}
+	return strings.Join(quoted, ",")
+}
+
+// conflict_clause produces the 'on conflict' clause with a leading space, the
+// clause is omitted for the default resolution.
+func conflict_clause(a ConflictAction) string {
+	if a == "" || a == Abort {
+		return ""
+	}
+	return " on conflict " + string(a)
+}
+
+func constraint_name(name string) string {
+	if name == "" {
+		return ""
+	}
+	return "constraint " + QuoteName(name) + " "
+}
+
+// ReferencesClause produces the 'references' part of the foreign key
+// definition.
+func (fk *ForeignKey) ReferencesClause() string {
+	s := "references " + QuoteName(fk.ParentTable)
+	if len(fk.ParentColumns) > 0 {
+		s += "(" + name_list(fk.ParentColumns) + ")"
+	}
+	if fk.OnUpdate != NoAction {
+		s += " on update " + string(fk.OnUpdate)
+	}
+	if fk.OnDelete != NoAction {
+		s += " on delete " + string(fk.OnDelete)
+	}
+	if fk.Deferrable != NotDeferrable {
+		s += " deferrable initially " + string(fk.Deferrable)
+	}
+	return s
 }
 
 type table_grid [][]string
@@ -20,7 +20,7 @@ func ExampleTable_CreateStatements() {
 		PK:           []string{"uid"},
 		WithoutRowID: true,
 		Indices: []*Index{
-			{Name: "idx_name", Columns: []string{"name"}, Unique: true},
+			{Name: "idx_name", Columns: []IndexColumn{{Name: "name"}}, Unique: true},
 		},
 	}
 
@@ -40,3 +40,31 @@ func ExampleTable_CreateStatements() {
 	// create unique index idx_name on MyTable(name);
 
 }
+
+func ExampleDatabase_CreateStatements() {
+
+	d := Database{
+		Tables: []*Table{
+			{Name: "t", Columns: []*Column{{Name: "id", Type: Int}}},
+		},
+		Views: []*View{
+			{Name: "top", SQL: "create view top as select id from [all] limit 1;"},
+			{Name: "all", SQL: "create view [all] as select id from t"},
+		},
+		Triggers: []*Trigger{
+			{Name: "t_ins", Table: "t", SQL: "create trigger t_ins after insert on t begin select 1; end"},
+		},
+	}
+
+	b := bytes.Buffer{}
+	d.CreateStatements(&b)
+	fmt.Print(b.String())
+	// Output:
+	// create table t (
+	//     id  int  not null
+	// );
+	// create view [all] as select id from t;
+	// create view top as select id from [all] limit 1;
+	// create trigger t_ins after insert on t begin select 1; end;
+
+}
@@ -0,0 +1,375 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/adnsv/go-db3/internal/sqltext"
+	"golang.org/x/exp/slices"
+)
+
+// DatabaseDiff is a structured set of changes that turns one database schema
+// into another, typically obtained by calling Diff.
+type DatabaseDiff struct {
+	AddedTables   []*Table     `json:"added_tables,omitempty"`
+	RemovedTables []*Table     `json:"removed_tables,omitempty"`
+	ChangedTables []*TableDiff `json:"changed_tables,omitempty"`
+}
+
+// TableDiff describes the changes within a table that exists in both
+// schemas.
+type TableDiff struct {
+	Name               string                       `json:"table"`
+	Have               *Table                       `json:"-"`
+	Want               *Table                       `json:"-"`
+	AddedColumns       []*Column                    `json:"added_columns,omitempty"`
+	RemovedColumns     []*Column                    `json:"removed_columns,omitempty"`
+	ChangedColumns     []*ColumnDiff                `json:"changed_columns,omitempty"`
+	AddedIndices       []*Index                     `json:"added_indices,omitempty"`
+	RemovedIndices     []*Index                     `json:"removed_indices,omitempty"`
+	ChangedIndices     []*Change[*Index]            `json:"changed_indices,omitempty"`
+	AddedForeignKeys   []*ForeignKey                `json:"added_foreign_keys,omitempty"`
+	RemovedForeignKeys []*ForeignKey                `json:"removed_foreign_keys,omitempty"`
+	ChangedForeignKeys []*Change[*ForeignKey]       `json:"changed_foreign_keys,omitempty"`
+	PK                 *Change[[]string]            `json:"pk,omitempty"`
+	PKOnConflict       *Change[ConflictAction]      `json:"pk_on_conflict,omitempty"`
+	AutoIncrement      *Change[bool]                `json:"autoincrement,omitempty"`
+	Uniques            *Change[[]*UniqueConstraint] `json:"uniques,omitempty"`
+	Checks             *Change[[]*CheckConstraint]  `json:"checks,omitempty"`
+	WithoutRowID       *Change[bool]                `json:"without_rowid,omitempty"`
+	Strict             *Change[bool]                `json:"strict,omitempty"`
+}
+
+// ColumnDiff describes a column that exists in both tables, but has a
+// different signature. Changes enlists the aspects that differ: "type",
+// "nullable", "default", "generated", "collate", "check", "on conflict".
+type ColumnDiff struct {
+	Name    string   `json:"name"`
+	Have    *Column  `json:"have"`
+	Want    *Column  `json:"want"`
+	Changes []string `json:"changes"`
+}
+
+// Change is a pair of values before and after the change.
+type Change[T any] struct {
+	Have T `json:"have"`
+	Want T `json:"want"`
+}
+
+// Diff compares two database schemas and returns the changes required to turn
+// have into want. Automatic indices (sqlite_autoindex_*) and internal tables
+// (sqlite_sequence, sqlite_stat*) are ignored as they are managed by sqlite
+// itself.
+func Diff(have, want *Database) *DatabaseDiff {
+	d := &DatabaseDiff{}
+	for _, w := range want.Tables {
+		if is_internal(w.Name) {
+			continue
+		}
+		if h, ok := have.FindTable(w.Name); !ok {
+			d.AddedTables = append(d.AddedTables, w)
+		} else if td := diff_table(h, w); !td.Empty() {
+			d.ChangedTables = append(d.ChangedTables, td)
+		}
+	}
+	for _, h := range have.Tables {
+		if !is_internal(h.Name) && !want.HasTable(h.Name) {
+			d.RemovedTables = append(d.RemovedTables, h)
+		}
+	}
+	return d
+}
+
+func diff_table(have, want *Table) *TableDiff {
+	d := &TableDiff{Name: want.Name, Have: have, Want: want}
+
+	for _, w := range want.Columns {
+		if h, ok := have.FindColumn(w.Name); !ok {
+			d.AddedColumns = append(d.AddedColumns, w)
+		} else if changes := column_changes(h, w); len(changes) > 0 {
+			d.ChangedColumns = append(d.ChangedColumns, &ColumnDiff{
+				Name: w.Name, Have: h, Want: w, Changes: changes})
+		}
+	}
+	for _, h := range have.Columns {
+		if _, ok := want.FindColumn(h.Name); !ok {
+			d.RemovedColumns = append(d.RemovedColumns, h)
+		}
+	}
+
+	for _, w := range want.Indices {
+		n := want.IndexName(w)
+		if is_auto_index(n) {
+			continue
+		}
+		if h, ok := find_index(have, n); !ok {
+			d.AddedIndices = append(d.AddedIndices, w)
+		} else if !h.CompatibleTo(w) {
+			d.ChangedIndices = append(d.ChangedIndices, &Change[*Index]{h, w})
+		}
+	}
+	for _, h := range have.Indices {
+		n := have.IndexName(h)
+		if is_auto_index(n) {
+			continue
+		}
+		if _, ok := find_index(want, n); !ok {
+			d.RemovedIndices = append(d.RemovedIndices, h)
+		}
+	}
+
+	for _, w := range want.ForeignKeys {
+		if h, ok := have.FindForeignKey(w.Columns...); !ok {
+			d.AddedForeignKeys = append(d.AddedForeignKeys, w)
+		} else if !h.CompatibleTo(w) {
+			d.ChangedForeignKeys = append(d.ChangedForeignKeys, &Change[*ForeignKey]{h, w})
+		}
+	}
+	for _, h := range have.ForeignKeys {
+		if _, ok := want.FindForeignKey(h.Columns...); !ok {
+			d.RemovedForeignKeys = append(d.RemovedForeignKeys, h)
+		}
+	}
+
+	if !slices.Equal(have.PK, want.PK) {
+		d.PK = &Change[[]string]{have.PK, want.PK}
+	}
+	if !same_conflict(have.PKOnConflict, want.PKOnConflict) {
+		d.PKOnConflict = &Change[ConflictAction]{have.PKOnConflict, want.PKOnConflict}
+	}
+	if have.AutoIncrement != want.AutoIncrement {
+		d.AutoIncrement = &Change[bool]{have.AutoIncrement, want.AutoIncrement}
+	}
+	if !same_constraints(have.Uniques, want.Uniques) {
+		d.Uniques = &Change[[]*UniqueConstraint]{have.Uniques, want.Uniques}
+	}
+	if !same_constraints(have.Checks, want.Checks) {
+		d.Checks = &Change[[]*CheckConstraint]{have.Checks, want.Checks}
+	}
+	if have.WithoutRowID != want.WithoutRowID {
+		d.WithoutRowID = &Change[bool]{have.WithoutRowID, want.WithoutRowID}
+	}
+	if have.Strict != want.Strict {
+		d.Strict = &Change[bool]{have.Strict, want.Strict}
+	}
+	return d
+}
+
+// column_changes enlists the aspects in which column signatures differ.
+func column_changes(have, want *Column) (changes []string) {
+	if !strings.EqualFold(string(NormalizeType(have.Type)), string(NormalizeType(want.Type))) {
+		changes = append(changes, "type")
+	}
+	if have.Nullable != want.Nullable {
+		changes = append(changes, "nullable")
+	}
+	if default_sql(have) != default_sql(want) {
+		changes = append(changes, "default")
+	}
+	if !same_expr(have.Generated, want.Generated) || have.Stored != want.Stored {
+		changes = append(changes, "generated")
+	}
+	if !strings.EqualFold(collation(have.Collate), collation(want.Collate)) {
+		changes = append(changes, "collate")
+	}
+	if !same_expr(have.Check, want.Check) {
+		changes = append(changes, "check")
+	}
+	if !same_conflict(have.OnConflict, want.OnConflict) {
+		changes = append(changes, "on conflict")
+	}
+	return
+}
+
+// same_constraints compares constraint lists disregarding their order.
+func same_constraints[T interface{ CompatibleTo(T) bool }](have, want []T) bool {
+	if len(have) != len(want) {
+		return false
+	}
+	matched := make([]bool, len(want))
+	for _, h := range have {
+		found := false
+		for i, w := range want {
+			if !matched[i] && h.CompatibleTo(w) {
+				matched[i], found = true, true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// default_sql returns the default value literal, treating explicit null
+// defaults as no default. String literals are requoted the same way as when
+// scanned.
+func default_sql(c *Column) string {
+	switch c.Default.(type) {
+	case nil, NULL:
+		return ""
+	}
+	if s := c.Default.SQLLiteral(); !strings.EqualFold(s, "null") {
+		return sqltext.Requote(s)
+	}
+	return ""
+}
+
+// find_index finds the index by its effective name, see Table.IndexName.
+func find_index(t *Table, name string) (*Index, bool) {
+	for _, i := range t.Indices {
+		if t.IndexName(i) == name {
+			return i, true
+		}
+	}
+	return nil, false
+}
+
+func is_auto_index(name string) bool {
+	return strings.HasPrefix(name, "sqlite_autoindex_")
+}
+
+func is_internal(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "sqlite_")
+}
+
+// Empty returns true if there are no differences.
+func (d *DatabaseDiff) Empty() bool {
+	return len(d.AddedTables) == 0 && len(d.RemovedTables) == 0 &&
+		len(d.ChangedTables) == 0
+}
+
+// Empty returns true if there are no differences.
+func (d *TableDiff) Empty() bool {
+	return len(d.AddedColumns) == 0 && len(d.RemovedColumns) == 0 &&
+		len(d.ChangedColumns) == 0 &&
+		len(d.AddedIndices) == 0 && len(d.RemovedIndices) == 0 &&
+		len(d.ChangedIndices) == 0 &&
+		len(d.AddedForeignKeys) == 0 && len(d.RemovedForeignKeys) == 0 &&
+		len(d.ChangedForeignKeys) == 0 &&
+		d.PK == nil && d.PKOnConflict == nil && d.AutoIncrement == nil &&
+		d.Uniques == nil && d.Checks == nil &&
+		d.WithoutRowID == nil && d.Strict == nil
+}
+
+// String produces a human-readable listing of the changes, one change per
+// line, prefixed with '+' for additions, '-' for removals and '~' for
+// modifications.
+func (d *DatabaseDiff) String() string {
+	b := &bytes.Buffer{}
+	for _, t := range d.AddedTables {
+		fmt.Fprintf(b, "+ table %s\n", t.Name)
+	}
+	for _, t := range d.RemovedTables {
+		fmt.Fprintf(b, "- table %s\n", t.Name)
+	}
+	for _, t := range d.ChangedTables {
+		fmt.Fprintf(b, "~ table %s\n", t.Name)
+		t.write_lines(b, "    ")
+	}
+	return b.String()
+}
+
+// String produces a human-readable listing of the changes within a table.
+func (d *TableDiff) String() string {
+	b := &bytes.Buffer{}
+	d.write_lines(b, "")
+	return b.String()
+}
+
+func (d *TableDiff) write_lines(b *bytes.Buffer, indent string) {
+	for _, c := range d.AddedColumns {
+		fmt.Fprintf(b, "%s+ column %s\n", indent, c.definition())
+	}
+	for _, c := range d.RemovedColumns {
+		fmt.Fprintf(b, "%s- column %s\n", indent, c.Name)
+	}
+	for _, c := range d.ChangedColumns {
+		fmt.Fprintf(b, "%s~ column %s: %s -> %s\n", indent, c.Name,
+			c.Have.definition(), c.Want.definition())
+	}
+	for _, i := range d.AddedIndices {
+		fmt.Fprintf(b, "%s+ index %s %s\n", indent, d.Want.IndexName(i), describe_index(i))
+	}
+	for _, i := range d.RemovedIndices {
+		fmt.Fprintf(b, "%s- index %s\n", indent, d.Have.IndexName(i))
+	}
+	for _, c := range d.ChangedIndices {
+		fmt.Fprintf(b, "%s~ index %s: %s -> %s\n", indent, d.Want.IndexName(c.Want),
+			describe_index(c.Have), describe_index(c.Want))
+	}
+	for _, fk := range d.AddedForeignKeys {
+		fmt.Fprintf(b, "%s+ foreign key %s\n", indent, fk)
+	}
+	for _, fk := range d.RemovedForeignKeys {
+		fmt.Fprintf(b, "%s- foreign key %s\n", indent, fk)
+	}
+	for _, c := range d.ChangedForeignKeys {
+		fmt.Fprintf(b, "%s~ foreign key (%s): %s -> %s\n", indent,
+			strings.Join(c.Want.Columns, ","),
+			c.Have.ReferencesClause(), c.Want.ReferencesClause())
+	}
+	if d.PK != nil {
+		fmt.Fprintf(b, "%s~ primary key: (%s) -> (%s)\n", indent,
+			strings.Join(d.PK.Have, ","), strings.Join(d.PK.Want, ","))
+	}
+	if d.PKOnConflict != nil {
+		fmt.Fprintf(b, "%s~ primary key on conflict: %s -> %s\n", indent,
+			conflict_name(d.PKOnConflict.Have), conflict_name(d.PKOnConflict.Want))
+	}
+	if d.AutoIncrement != nil {
+		fmt.Fprintf(b, "%s~ autoincrement: %v -> %v\n", indent,
+			d.AutoIncrement.Have, d.AutoIncrement.Want)
+	}
+	if d.Uniques != nil {
+		fmt.Fprintf(b, "%s~ unique constraints: %s -> %s\n", indent,
+			describe_uniques(d.Uniques.Have), describe_uniques(d.Uniques.Want))
+	}
+	if d.Checks != nil {
+		fmt.Fprintf(b, "%s~ check constraints: %s -> %s\n", indent,
+			describe_checks(d.Checks.Have), describe_checks(d.Checks.Want))
+	}
+	if d.WithoutRowID != nil {
+		fmt.Fprintf(b, "%s~ without rowid: %v -> %v\n", indent,
+			d.WithoutRowID.Have, d.WithoutRowID.Want)
+	}
+	if d.Strict != nil {
+		fmt.Fprintf(b, "%s~ strict: %v -> %v\n", indent, d.Strict.Have, d.Strict.Want)
+	}
+}
+
+func describe_index(idx *Index) string {
+	s := "(" + index_columns(idx) + ")"
+	if idx.Unique {
+		s = "unique " + s
+	}
+	if idx.Where != "" {
+		s += " where " + idx.Where
+	}
+	return s
+}
+
+func conflict_name(a ConflictAction) string {
+	if a == "" {
+		return string(Abort)
+	}
+	return string(a)
+}
+
+func describe_uniques(uu []*UniqueConstraint) string {
+	ss := make([]string, len(uu))
+	for i, u := range uu {
+		ss[i] = "(" + strings.Join(u.Columns, ",") + ")" + conflict_clause(u.OnConflict)
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
+
+func describe_checks(cc []*CheckConstraint) string {
+	ss := make([]string, len(cc))
+	for i, c := range cc {
+		ss[i] = "(" + c.Expr + ")"
+	}
+	return "[" + strings.Join(ss, ", ") + "]"
+}
@@ -0,0 +1,104 @@
+package schema
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func ExampleDiff() {
+
+	have := &Database{Tables: []*Table{
+		{
+			Name: "users",
+			Columns: []*Column{
+				{Name: "id", Type: Int64},
+				{Name: "name", Type: Text, Nullable: true},
+				{Name: "age", Type: Int},
+				{Name: "legacy", Type: Text},
+			},
+			PK: []string{"id"},
+			Indices: []*Index{
+				{Name: "users_name", Columns: []IndexColumn{{Name: "name"}}},
+				{Name: "users_age", Columns: []IndexColumn{{Name: "age"}}},
+			},
+		},
+		{Name: "obsolete", Columns: []*Column{{Name: "x"}}},
+	}}
+
+	want := &Database{Tables: []*Table{
+		{
+			Name: "users",
+			Columns: []*Column{
+				{Name: "id", Type: "BIGINT"},
+				{Name: "name", Type: Text},
+				{Name: "age", Type: Int, Default: LiteralInt(0)},
+				{Name: "email", Type: Text, Nullable: true},
+			},
+			PK: []string{"id"},
+			Indices: []*Index{
+				{Name: "users_name", Columns: []IndexColumn{{Name: "name"}}, Unique: true},
+				{Columns: []IndexColumn{{Name: "email"}}},
+			},
+			Strict: true,
+		},
+		{Name: "groups", Columns: []*Column{{Name: "id", Type: Int64}}},
+	}}
+
+	d := Diff(have, want)
+	fmt.Print(d)
+
+	j, _ := json.Marshal(d.ChangedTables[0].ChangedColumns[0])
+	fmt.Println(string(j))
+
+	fmt.Println(Diff(want, want).Empty())
+
+	// Output:
+	// + table groups
+	// - table obsolete
+	// ~ table users
+	//     + column email text
+	//     - column legacy
+	//     ~ column name: name text -> name text not null
+	//     ~ column age: age int not null -> age int not null default 0
+	//     + index users_email_index (email)
+	//     - index users_age
+	//     ~ index users_name: (name) -> unique (name)
+	//     ~ strict: false -> true
+	// {"name":"name","have":{"name":"name","type":"text","nullable":true},"want":{"name":"name","type":"text"},"changes":["nullable"]}
+	// true
+}
+
+func ExampleDiff_constraints() {
+
+	have := &Database{Tables: []*Table{{
+		Name: "t",
+		Columns: []*Column{
+			{Name: "a", Type: Int},
+			{Name: "b", Type: Text, Nullable: true},
+		},
+		Uniques: []*UniqueConstraint{{Columns: []string{"a"}}},
+	}}}
+
+	want := &Database{Tables: []*Table{{
+		Name: "t",
+		Columns: []*Column{
+			{Name: "a", Type: Int, Check: "a > 0"},
+			{Name: "b", Type: Text, Nullable: true, Collate: "nocase"},
+		},
+		Uniques: []*UniqueConstraint{{Columns: []string{"a"}, OnConflict: Replace}},
+		Checks:  []*CheckConstraint{{Expr: "a <> b"}},
+	}}}
+
+	fmt.Print(Diff(have, want))
+
+	stmts, _ := PlanMigration(have, want, NoDrop)
+	fmt.Println(len(stmts) > 0)
+
+	// Output:
+	// ~ table t
+	//     ~ column a: a int not null -> a int not null check (a > 0)
+	//     ~ column b: b text -> b text collate nocase
+	//     ~ unique constraints: [(a)] -> [(a) on conflict replace]
+	//     ~ check constraints: [] -> [(a <> b)]
+	// true
+}
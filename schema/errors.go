@@ -37,6 +37,28 @@ type ErrIndices struct {
 	Incompatible ErrIncompatibleIndices
 }
 
+// ErrMissingForeignKeys is a list of foreign key descriptions that can be used
+// as the 'missing foreign keys' error.
+type ErrMissingForeignKeys []string
+
+// ErrIncompatibleForeignKeys is a list of foreign key descriptions that can be
+// used as the 'incompatible foreign keys' error.
+type ErrIncompatibleForeignKeys []string
+
+// ErrForeignKeys enlists missing and incompatible foreign keys in a table.
+type ErrForeignKeys struct {
+	Missing      ErrMissingForeignKeys
+	Incompatible ErrIncompatibleForeignKeys
+}
+
+// ErrRebuildRequired is a list of table names that can not be migrated
+// without rebuilding.
+type ErrRebuildRequired []string
+
+// ErrForeignKeyViolations is a list of table names that contain rows
+// violating foreign key constraints.
+type ErrForeignKeyViolations []string
+
 // Error implements support for the standard error interface.
 func (e ErrMissingTables) Error() string { return msg("missing tables", e) }
 
@@ -82,5 +104,32 @@ func (e *ErrIndices) Error() string {
 	return b.String()
 }
 
+// Error implements support for the standard error interface.
+func (e ErrMissingForeignKeys) Error() string { return msg("missing foreign keys", e) }
+
+// Error implements support for the standard error interface.
+func (e ErrIncompatibleForeignKeys) Error() string { return msg("incompatible foreign keys", e) }
+
+// Error implements support for the standard error interface.
+func (e *ErrForeignKeys) Error() string {
+	b := bytes.Buffer{}
+	b.WriteString("incompatible table foreign keys")
+	if len(e.Missing) > 0 {
+		b.WriteString(", ")
+		b.WriteString(e.Missing.Error())
+	}
+	if len(e.Incompatible) > 0 {
+		b.WriteString(", ")
+		b.WriteString(e.Incompatible.Error())
+	}
+	return b.String()
+}
+
+// Error implements support for the standard error interface.
+func (e ErrRebuildRequired) Error() string { return msg("tables require rebuild", e) }
+
+// Error implements support for the standard error interface.
+func (e ErrForeignKeyViolations) Error() string { return msg("foreign key violations in tables", e) }
+
 func joined[T ~[]string](names T) string           { return strings.Join([]string(names), ", ") }
 func msg[T ~[]string](subj string, names T) string { return subj + ": " + joined(names) }
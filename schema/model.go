@@ -1,41 +1,181 @@
 package schema
 
 import (
+	"fmt"
+	"strings"
+
+	"github.com/adnsv/go-db3/internal/sqltext"
+
+	"golang.org/x/exp/slices"
 	"gopkg.in/yaml.v3"
 )
 
 // Database contains table schemas, typically obtained when calling the Scan
 // routine on a database connection.
 type Database struct {
-	Tables []*Table `json:"tables" yaml:"tables"`
+	Tables   []*Table   `json:"tables" yaml:"tables"`
+	Views    []*View    `json:"views,omitempty" yaml:"views,omitempty"`
+	Triggers []*Trigger `json:"triggers,omitempty" yaml:"triggers,omitempty"`
 }
 
 // Table contains the descriptions for columns and indices within a table.
+// AutoIncrement applies to the rowid alias column and is ignored for tables
+// without one, see RowIDAlias.
 type Table struct {
-	Name         string    `json:"table" yaml:"table"`
-	Columns      []*Column `json:"columns,omitempty" yaml:"columns,omitempty"`
-	Indices      []*Index  `json:"indices,omitempty" yaml:"indices,omitempty"`
-	PK           []string  `json:"pk,omitempty" yaml:"pk,omitempty,flow"`
-	WithoutRowID bool      `json:"without_rowid,omitempty" yaml:"without_rowid,omitempty"`
-	Strict       bool      `json:"strict,omitempty" yaml:"strict,omitempty"`
+	Name          string              `json:"table" yaml:"table"`
+	Columns       []*Column           `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Indices       []*Index            `json:"indices,omitempty" yaml:"indices,omitempty"`
+	PK            []string            `json:"pk,omitempty" yaml:"pk,omitempty,flow"`
+	PKOnConflict  ConflictAction      `json:"pk_on_conflict,omitempty" yaml:"pk_on_conflict,omitempty"`
+	AutoIncrement bool                `json:"autoincrement,omitempty" yaml:"autoincrement,omitempty"`
+	Uniques       []*UniqueConstraint `json:"uniques,omitempty" yaml:"uniques,omitempty"`
+	Checks        []*CheckConstraint  `json:"checks,omitempty" yaml:"checks,omitempty"`
+	ForeignKeys   []*ForeignKey       `json:"foreign_keys,omitempty" yaml:"foreign_keys,omitempty"`
+	WithoutRowID  bool                `json:"without_rowid,omitempty" yaml:"without_rowid,omitempty"`
+	Strict        bool                `json:"strict,omitempty" yaml:"strict,omitempty"`
 }
 
-// Column contains schema scan results for column within a table.
+// Column contains schema scan results for column within a table. Check is
+// the expression of the column check constraint, OnConflict applies to the
+// not null constraint. Generated is the expression of a generated column,
+// which is virtual unless Stored is set. Expression defaults are kept in
+// parentheses, as they are declared.
 type Column struct {
-	Name     string     `json:"name" yaml:"name"`
-	Type     ColumnType `json:"type,omitempty" yaml:"type,omitempty"`
-	Nullable bool       `json:"nullable,omitempty" yaml:"nullable,omitempty"`
-	Default  Literal    `json:"default,omitempty" yaml:"default,omitempty"`
-	Comment  string     `json:"comment,omitempty" yaml:"comment,omitempty"`
+	Name       string         `json:"name" yaml:"name"`
+	Type       ColumnType     `json:"type,omitempty" yaml:"type,omitempty"`
+	Nullable   bool           `json:"nullable,omitempty" yaml:"nullable,omitempty"`
+	Default    Literal        `json:"default,omitempty" yaml:"default,omitempty"`
+	Generated  string         `json:"generated,omitempty" yaml:"generated,omitempty"`
+	Stored     bool           `json:"stored,omitempty" yaml:"stored,omitempty"`
+	Collate    string         `json:"collate,omitempty" yaml:"collate,omitempty"`
+	Check      string         `json:"check,omitempty" yaml:"check,omitempty"`
+	OnConflict ConflictAction `json:"on_conflict,omitempty" yaml:"on_conflict,omitempty"`
+	Comment    string         `json:"comment,omitempty" yaml:"comment,omitempty"`
+}
+
+// UniqueConstraint is a table-level unique constraint. Unlike indices, unique
+// constraints are part of the table definition, sqlite backs them with
+// automatic indices.
+type UniqueConstraint struct {
+	Name       string         `json:"name,omitempty" yaml:"name,omitempty"`
+	Columns    []string       `json:"columns" yaml:"columns,flow"`
+	OnConflict ConflictAction `json:"on_conflict,omitempty" yaml:"on_conflict,omitempty"`
+}
+
+// CheckConstraint is a table-level check constraint.
+type CheckConstraint struct {
+	Name string `json:"name,omitempty" yaml:"name,omitempty"`
+	Expr string `json:"expr" yaml:"expr"`
 }
 
-// Index contains schema scan results for table's index.
+// ConflictAction is the conflict resolution algorithm of not null, primary
+// key and unique constraints. Empty value is equivalent to Abort.
+type ConflictAction string
+
+const (
+	Rollback = ConflictAction("rollback")
+	Abort    = ConflictAction("abort")
+	Fail     = ConflictAction("fail")
+	Ignore   = ConflictAction("ignore")
+	Replace  = ConflictAction("replace")
+)
+
+// Index contains schema scan results for table's index. Where is the
+// predicate of a partial index.
 type Index struct {
-	Name    string   `json:"name" yaml:"name"`
-	Unique  bool     `json:"unique,omitempty" yaml:"unique,omitempty"`
-	Columns []string `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Name    string        `json:"name" yaml:"name"`
+	Unique  bool          `json:"unique,omitempty" yaml:"unique,omitempty"`
+	Columns []IndexColumn `json:"columns,omitempty" yaml:"columns,omitempty"`
+	Where   string        `json:"where,omitempty" yaml:"where,omitempty"`
+}
+
+// IndexColumn is an entry within the index column list. Name is either a
+// column name or a parenthesized expression, e.g. "(lower(name))". Empty
+// Collation is equivalent to binary.
+//
+// In JSON and YAML the entry is represented by its sql text, e.g. "name" or
+// "name collate nocase desc".
+type IndexColumn struct {
+	Name      string
+	Collation string
+	Desc      bool
+}
+
+// ForeignKey contains the description of a foreign key constraint within a
+// table. Empty ParentColumns refer to the primary key of the parent table.
+type ForeignKey struct {
+	Columns       []string         `json:"columns" yaml:"columns,flow"`
+	ParentTable   string           `json:"parent_table" yaml:"parent_table"`
+	ParentColumns []string         `json:"parent_columns,omitempty" yaml:"parent_columns,omitempty,flow"`
+	OnUpdate      ForeignKeyAction `json:"on_update,omitempty" yaml:"on_update,omitempty"`
+	OnDelete      ForeignKeyAction `json:"on_delete,omitempty" yaml:"on_delete,omitempty"`
+	Deferrable    Deferrable       `json:"deferrable,omitempty" yaml:"deferrable,omitempty"`
+}
+
+// View contains the description of a view. SQL is the complete 'create view'
+// statement. Columns are resolved by sqlite when the database is scanned, they
+// are not used when the view is created.
+type View struct {
+	Name    string    `json:"view" yaml:"view"`
+	SQL     string    `json:"sql" yaml:"sql"`
+	Columns []*Column `json:"columns,omitempty" yaml:"columns,omitempty"`
 }
 
+// Trigger contains the description of a trigger. SQL is the complete 'create
+// trigger' statement, the other fields describe it and are not used when the
+// trigger is created.
+type Trigger struct {
+	Name    string        `json:"trigger" yaml:"trigger"`
+	Table   string        `json:"table" yaml:"table"`
+	Timing  TriggerTiming `json:"timing,omitempty" yaml:"timing,omitempty"`
+	Event   TriggerEvent  `json:"event,omitempty" yaml:"event,omitempty"`
+	Columns []string      `json:"columns,omitempty" yaml:"columns,omitempty,flow"`
+	SQL     string        `json:"sql" yaml:"sql"`
+}
+
+// TriggerTiming specifies when the trigger fires relative to the statement.
+// Empty value is equivalent to Before.
+type TriggerTiming string
+
+const (
+	Before    = TriggerTiming("before")
+	After     = TriggerTiming("after")
+	InsteadOf = TriggerTiming("instead of")
+)
+
+// TriggerEvent specifies the statement that fires the trigger, Columns of the
+// update triggers enlist the columns of 'update of' clause.
+type TriggerEvent string
+
+const (
+	DeleteEvent = TriggerEvent("delete")
+	InsertEvent = TriggerEvent("insert")
+	UpdateEvent = TriggerEvent("update")
+)
+
+// Deferrable specifies whether the checks of a foreign key constraint can be
+// deferred to the end of the transaction. Empty value is equivalent to
+// NotDeferrable.
+type Deferrable string
+
+const (
+	NotDeferrable      = Deferrable("")
+	InitiallyImmediate = Deferrable("immediate")
+	InitiallyDeferred  = Deferrable("deferred")
+)
+
+// ForeignKeyAction specifies what happens to child rows when the parent key
+// is updated or deleted. Empty value is equivalent to NoAction.
+type ForeignKeyAction string
+
+const (
+	NoAction   = ForeignKeyAction("")
+	Restrict   = ForeignKeyAction("restrict")
+	SetNull    = ForeignKeyAction("set null")
+	SetDefault = ForeignKeyAction("set default")
+	Cascade    = ForeignKeyAction("cascade")
+)
+
 func (db *Database) HasTable(tablename string) bool {
 	for _, t := range db.Tables {
 		if t.Name == tablename {
@@ -45,6 +185,33 @@ func (db *Database) HasTable(tablename string) bool {
 	return false
 }
 
+func (db *Database) FindTable(tablename string) (*Table, bool) {
+	for _, t := range db.Tables {
+		if t.Name == tablename {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+func (db *Database) FindView(viewname string) (*View, bool) {
+	for _, v := range db.Views {
+		if v.Name == viewname {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (db *Database) FindTrigger(triggername string) (*Trigger, bool) {
+	for _, t := range db.Triggers {
+		if t.Name == triggername {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
 // CheckTables validates existance of the specified tables.
 func (db *Database) CheckTables(names ...string) (missing ErrMissingTables) {
 	for _, n := range names {
@@ -64,6 +231,20 @@ func (t *Table) FindColumn(columnname string) (*Column, bool) {
 	return nil, false
 }
 
+// RowIDAlias returns the column that is an alias for the rowid: the single
+// primary key column of a rowid table declared with the 'integer' type. Nil
+// is returned if there is no such column.
+func (t *Table) RowIDAlias() *Column {
+	if len(t.PK) != 1 || t.WithoutRowID {
+		return nil
+	}
+	c, ok := t.FindColumn(t.PK[0])
+	if !ok || !strings.EqualFold(string(c.Type), "integer") {
+		return nil
+	}
+	return c
+}
+
 func (t *Table) ColumnMapping() map[string]*Column {
 	m := make(map[string]*Column, len(t.Columns))
 	for _, c := range t.Columns {
@@ -81,6 +262,74 @@ func (t *Table) FindIndex(indexname string) (*Index, bool) {
 	return nil, false
 }
 
+// IsExpression checks if the index column entry is an expression.
+func (c IndexColumn) IsExpression() bool {
+	return strings.HasPrefix(c.Name, "(")
+}
+
+// String produces the sql text of the entry.
+func (c IndexColumn) String() string {
+	s := c.Name
+	if !c.IsExpression() {
+		s = QuoteName(s)
+	}
+	if c.Collation != "" {
+		s += " collate " + QuoteName(c.Collation)
+	}
+	if c.Desc {
+		s += " desc"
+	}
+	return s
+}
+
+func (c IndexColumn) MarshalText() ([]byte, error) {
+	return []byte(c.String()), nil
+}
+
+// UnmarshalText parses the sql text of the entry, expressions are
+// parenthesized if necessary.
+func (c *IndexColumn) UnmarshalText(b []byte) error {
+	p, err := sqltext.NewParser("(" + string(b) + ")")
+	if err != nil {
+		return err
+	}
+	cc, err := p.IndexedColumns()
+	if err != nil {
+		return err
+	}
+	if len(cc) != 1 || !p.AtEnd() {
+		return fmt.Errorf("invalid index column %q", b)
+	}
+	*c = IndexColumn{Name: cc[0].Name, Collation: cc[0].Collation, Desc: cc[0].Desc}
+	if c.Name == "" {
+		c.Name = parenthesized(cc[0].Expr)
+	}
+	return nil
+}
+
+// parenthesized wraps the expression in parentheses unless it is already
+// enclosed in them.
+func parenthesized(expr string) string {
+	tt, err := sqltext.Tokenize(expr)
+	if err == nil && len(tt) > 2 && tt[0].IsPunct("(") {
+		depth := 0
+		for i, t := range tt {
+			if t.IsPunct("(") {
+				depth++
+			} else if t.IsPunct(")") {
+				depth--
+				if depth == 0 {
+					if i == len(tt)-2 {
+						return expr
+					}
+					break
+				}
+			}
+		}
+	}
+	return "(" + expr + ")"
+}
+
 func (t *Table) IndexMapping() map[string]*Index {
 	m := make(map[string]*Index, len(t.Indices))
 	for _, i := range t.Indices {
@@ -89,6 +338,24 @@ func (t *Table) IndexMapping() map[string]*Index {
 	return m
 }
 
+// FindForeignKey returns the foreign key constraint on the specified child
+// columns.
+func (t *Table) FindForeignKey(columns ...string) (*ForeignKey, bool) {
+	for _, fk := range t.ForeignKeys {
+		if slices.Equal(fk.Columns, columns) {
+			return fk, true
+		}
+	}
+	return nil, false
+}
+
+// String returns a short description of the foreign key, e.g. "(a,b) ->
+// parent(x,y)".
+func (fk *ForeignKey) String() string {
+	return "(" + strings.Join(fk.Columns, ",") + ") -> " +
+		fk.ParentTable + "(" + strings.Join(fk.ParentColumns, ",") + ")"
+}
+
 func (c *Column) MarshalYAML() (any, error) {
 	// get Columns to appear with flow style
 	type flat Column
@@ -115,6 +382,16 @@ func (c *Column) UnmarshalYAML(node *yaml.Node) error {
 		case "default":
 			// this fixes issue where Literal is not unmarshalled correctly
 			c.Default = parseLiteral(value.Value)
+		case "generated":
+			c.Generated = value.Value
+		case "stored":
+			c.Stored = value.Value == "true"
+		case "collate":
+			c.Collate = value.Value
+		case "check":
+			c.Check = value.Value
+		case "on_conflict":
+			c.OnConflict = ConflictAction(value.Value)
 		case "comment":
 			c.Comment = value.Value
 		}
@@ -131,3 +408,33 @@ func (i *Index) MarshalYAML() (any, error) {
 	n.Style = yaml.FlowStyle
 	return &n, nil
 }
+
+func (fk *ForeignKey) MarshalYAML() (any, error) {
+	// get ForeignKeys to appear with flow style
+	type flat ForeignKey
+	f := (*flat)(fk)
+	n := yaml.Node{}
+	n.Encode(f)
+	n.Style = yaml.FlowStyle
+	return &n, nil
+}
+
+func (u *UniqueConstraint) MarshalYAML() (any, error) {
+	// get Uniques to appear with flow style
+	type flat UniqueConstraint
+	f := (*flat)(u)
+	n := yaml.Node{}
+	n.Encode(f)
+	n.Style = yaml.FlowStyle
+	return &n, nil
+}
+
+func (c *CheckConstraint) MarshalYAML() (any, error) {
+	// get Checks to appear with flow style
+	type flat CheckConstraint
+	f := (*flat)(c)
+	n := yaml.Node{}
+	n.Encode(f)
+	n.Style = yaml.FlowStyle
+	return &n, nil
+}
@@ -77,6 +77,16 @@ func NormalizeNames(t *Table, uppercase bool) {
 	for _, i := range t.Indices {
 		i.Name = norm(i.Name)
 	}
+
+	for _, fk := range t.ForeignKeys {
+		fk.ParentTable = norm(fk.ParentTable)
+		for i := range fk.Columns {
+			fk.Columns[i] = norm(fk.Columns[i])
+		}
+		for i := range fk.ParentColumns {
+			fk.ParentColumns[i] = norm(fk.ParentColumns[i])
+		}
+	}
 }
 
 func SortColumns(t *Table) {
@@ -0,0 +1,348 @@
+package schema
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+type MigrateFlag int
+
+const (
+	// NoRebuild makes PlanMigration fail with ErrRebuildRequired instead of
+	// rebuilding tables that can not be altered in place.
+	NoRebuild = MigrateFlag(1)
+
+	// NoDrop keeps tables, columns, indices and foreign keys that exist in
+	// the current schema, but are absent from the desired one.
+	NoDrop = MigrateFlag(2)
+)
+
+// PlanMigration produces an ordered list of SQL statements that migrates a
+// database with the schema have to the schema want.
+//
+// Changes are applied in place where sqlite allows it: new columns are added
+// with 'alter table add column', changed indices are dropped and re-created.
+// All other changes are done by rebuilding the table: a new table is created,
+// populated from the old one, the old table is dropped and the new one is
+// renamed in its place. Views and triggers that refer to the rebuilt tables
+// are dropped beforehand and re-created afterwards, and each rebuilt table is
+// followed by 'pragma foreign_key_check', see Apply.
+//
+// When the plan contains rebuilds, it should be executed within a transaction
+// with foreign key enforcement disabled, see
+// https://www.sqlite.org/lang_altertable.html#otheralter
+func PlanMigration(have, want *Database, flags ...MigrateFlag) ([]string, error) {
+	no_rebuild, no_drop := false, false
+	for _, f := range flags {
+		switch f {
+		case NoRebuild:
+			no_rebuild = true
+		case NoDrop:
+			no_drop = true
+		}
+	}
+
+	d := Diff(have, want)
+	stmts := []string{}
+	rebuilds := ErrRebuildRequired{}
+	views, triggers := map[*View]bool{}, map[*Trigger]bool{}
+
+	for _, t := range d.AddedTables {
+		stmts = append(stmts, create_statements(without_auto_indices(t))...)
+	}
+
+	for _, td := range d.ChangedTables {
+		target := td.Want
+		if no_drop {
+			target = td.kept()
+		}
+
+		if td.needs_rebuild(no_drop) {
+			if no_rebuild {
+				rebuilds = append(rebuilds, td.Name)
+				continue
+			}
+			vv, tt := dependents(have, td.Name)
+			for _, tr := range tt {
+				if !triggers[tr] {
+					triggers[tr] = true
+					stmts = append(stmts, "drop trigger "+QuoteName(tr.Name)+";")
+				}
+			}
+			for i := len(vv) - 1; i >= 0; i-- {
+				if !views[vv[i]] {
+					views[vv[i]] = true
+					stmts = append(stmts, "drop view "+QuoteName(vv[i].Name)+";")
+				}
+			}
+			tmp := unused_name("new_"+target.Name, have, want)
+			stmts = append(stmts, rebuild_statements(td.Have, target, tmp)...)
+			continue
+		}
+
+		if !no_drop {
+			for _, idx := range td.RemovedIndices {
+				stmts = append(stmts, "drop index "+QuoteName(td.Have.IndexName(idx))+";")
+			}
+		}
+		for _, c := range td.ChangedIndices {
+			stmts = append(stmts, "drop index "+QuoteName(td.Have.IndexName(c.Have))+";")
+			stmts = append(stmts, target.CreateIndexStatement(c.Want))
+		}
+		for _, c := range td.AddedColumns {
+			stmts = append(stmts, "alter table "+QuoteName(target.Name)+" add column "+c.definition()+";")
+		}
+		for _, idx := range td.AddedIndices {
+			stmts = append(stmts, target.CreateIndexStatement(idx))
+		}
+	}
+
+	for _, v := range ordered_views(have.Views) {
+		if views[v] {
+			stmts = append(stmts, strings.TrimSpace(statement(v.SQL)))
+		}
+	}
+	for _, tr := range have.Triggers {
+		if triggers[tr] {
+			stmts = append(stmts, strings.TrimSpace(statement(tr.SQL)))
+		}
+	}
+
+	if !no_drop {
+		for _, t := range d.RemovedTables {
+			stmts = append(stmts, "drop table "+QuoteName(t.Name)+";")
+		}
+	}
+
+	if len(rebuilds) > 0 {
+		return nil, rebuilds
+	}
+	return stmts, nil
+}
+
+// needs_rebuild returns true if the changes can not be done with 'alter
+// table' and index statements.
+func (d *TableDiff) needs_rebuild(no_drop bool) bool {
+	if len(d.ChangedColumns) > 0 || d.PK != nil || d.PKOnConflict != nil || d.AutoIncrement != nil ||
+		d.WithoutRowID != nil || d.Strict != nil ||
+		len(d.AddedForeignKeys) > 0 || len(d.ChangedForeignKeys) > 0 {
+		return true
+	}
+	if !no_drop && (len(d.RemovedColumns) > 0 || len(d.RemovedForeignKeys) > 0 ||
+		d.Uniques != nil || d.Checks != nil) {
+		return true
+	}
+	if d.Uniques != nil && len(unmatched(d.Uniques.Want, d.Uniques.Have)) > 0 {
+		return true
+	}
+	if d.Checks != nil && len(unmatched(d.Checks.Want, d.Checks.Have)) > 0 {
+		return true
+	}
+	for _, c := range d.AddedColumns {
+		if !can_add_column(d.Want, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// can_add_column checks the restrictions sqlite imposes on 'alter table add
+// column', see https://www.sqlite.org/lang_altertable.html#altertabaddcol
+func can_add_column(t *Table, c *Column) bool {
+	for _, n := range t.PK {
+		if n == c.Name {
+			return false
+		}
+	}
+	switch d := c.Default.(type) {
+	case CurrentTime, CurrentDate, CurrentTimestamp:
+		return false
+	case RawLiteral:
+		if strings.HasPrefix(string(d), "(") {
+			return false
+		}
+	}
+	if c.Generated != "" {
+		return !c.Stored
+	}
+	if !c.Nullable && default_sql(c) == "" {
+		return false
+	}
+	return true
+}
+
+// kept produces the desired table extended with columns, indices and foreign
+// keys that exist only in the current table.
+func (d *TableDiff) kept() *Table {
+	t := *d.Want
+	if len(d.RemovedColumns) > 0 {
+		t.Columns = append(append([]*Column{}, t.Columns...), d.RemovedColumns...)
+	}
+	if len(d.RemovedIndices) > 0 {
+		t.Indices = append(append([]*Index{}, t.Indices...), d.RemovedIndices...)
+	}
+	if len(d.RemovedForeignKeys) > 0 {
+		t.ForeignKeys = append(append([]*ForeignKey{}, t.ForeignKeys...), d.RemovedForeignKeys...)
+	}
+	if d.Uniques != nil {
+		t.Uniques = append(append([]*UniqueConstraint{}, t.Uniques...), unmatched(d.Uniques.Have, d.Uniques.Want)...)
+	}
+	if d.Checks != nil {
+		t.Checks = append(append([]*CheckConstraint{}, t.Checks...), unmatched(d.Checks.Have, d.Checks.Want)...)
+	}
+	return &t
+}
+
+// unmatched enlists constraints in aa that have no compatible counterpart in
+// bb.
+func unmatched[T interface{ CompatibleTo(T) bool }](aa, bb []T) []T {
+	r := []T{}
+	for _, a := range aa {
+		found := false
+		for _, b := range bb {
+			if a.CompatibleTo(b) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			r = append(r, a)
+		}
+	}
+	return r
+}
+
+// dependents enlists views and triggers that refer to the table, directly or
+// through other views. Sqlite re-checks them when the new table is renamed,
+// they fail while the old table is gone.
+func dependents(db *Database, table string) ([]*View, []*Trigger) {
+	names := map[string]bool{strings.ToLower(table): true}
+	refers := func(s string) bool {
+		for _, n := range referenced_names(s) {
+			if names[n] {
+				return true
+			}
+		}
+		return false
+	}
+
+	views := []*View{}
+	for _, v := range ordered_views(db.Views) {
+		if refers(v.SQL) {
+			names[strings.ToLower(v.Name)] = true
+			views = append(views, v)
+		}
+	}
+	triggers := []*Trigger{}
+	for _, tr := range db.Triggers {
+		if names[strings.ToLower(tr.Table)] || refers(tr.SQL) {
+			triggers = append(triggers, tr)
+		}
+	}
+	return views, triggers
+}
+
+// rebuild_statements produces the 'create new table, copy, drop, rename'
+// sequence, the new table is created as tmp_name.
+func rebuild_statements(have, want *Table, tmp_name string) []string {
+	tmp := *without_auto_indices(want)
+	tmp.Name = tmp_name
+	indices := tmp.Indices
+	tmp.Indices = nil
+
+	stmts := create_statements(&tmp)
+
+	columns, values := []string{}, []string{}
+	for _, w := range want.Columns {
+		h, ok := have.FindColumn(w.Name)
+		if !ok || w.Generated != "" {
+			// generated columns are computed by the new table, the values of
+			// the old ones are copied like any other column
+			continue
+		}
+		n := QuoteName(w.Name)
+		v := n
+		if h.Nullable && !w.Nullable && default_sql(w) != "" {
+			v = "coalesce(" + n + ", " + string_literal(default_sql(w)) + ")"
+		}
+		columns = append(columns, n)
+		values = append(values, v)
+	}
+	if len(columns) > 0 {
+		stmts = append(stmts, "insert into "+QuoteName(tmp.Name)+" ("+strings.Join(columns, ", ")+
+			") select "+strings.Join(values, ", ")+" from "+QuoteName(have.Name)+";")
+	}
+
+	stmts = append(stmts,
+		"drop table "+QuoteName(have.Name)+";",
+		"alter table "+QuoteName(tmp.Name)+" rename to "+QuoteName(want.Name)+";")
+
+	for _, idx := range indices {
+		stmts = append(stmts, want.CreateIndexStatement(idx))
+	}
+	return append(stmts, "pragma foreign_key_check("+QuoteName(want.Name)+");")
+}
+
+// unused_name returns base, or base with a numeric suffix, that does not
+// collide with the names of tables, indices, views and triggers in dbs.
+func unused_name(base string, dbs ...*Database) string {
+	taken := map[string]bool{}
+	for _, db := range dbs {
+		for _, t := range db.Tables {
+			taken[strings.ToLower(t.Name)] = true
+			for _, idx := range t.Indices {
+				taken[strings.ToLower(t.IndexName(idx))] = true
+			}
+		}
+		for _, v := range db.Views {
+			taken[strings.ToLower(v.Name)] = true
+		}
+		for _, tr := range db.Triggers {
+			taken[strings.ToLower(tr.Name)] = true
+		}
+	}
+	name := base
+	for i := 2; taken[strings.ToLower(name)]; i++ {
+		name = fmt.Sprintf("%s_%d", base, i)
+	}
+	return name
+}
+
+// string_literal reverts the double quotes of string defaults to single
+// quotes, within expressions double quoted text may resolve to a column.
+func string_literal(s string) string {
+	n := len(s)
+	if n >= 2 && s[0] == '"' && s[n-1] == '"' {
+		t := strings.ReplaceAll(s[1:n-1], `""`, `"`)
+		return "'" + strings.ReplaceAll(t, "'", "''") + "'"
+	}
+	return s
+}
+
+// create_statements splits the output of Table.CreateStatements into
+// individual statements.
+func create_statements(t *Table) []string {
+	b := bytes.Buffer{}
+	t.CreateStatements(&b)
+	stmts := []string{}
+	for _, s := range strings.SplitAfter(b.String(), ";\n") {
+		if s = strings.TrimSuffix(s, "\n"); s != "" {
+			stmts = append(stmts, s)
+		}
+	}
+	return stmts
+}
+
+// without_auto_indices produces a shallow copy of the table without indices
+// that sqlite creates automatically for unique and primary key constraints.
+func without_auto_indices(t *Table) *Table {
+	c := *t
+	c.Indices = nil
+	for _, idx := range t.Indices {
+		if !is_auto_index(t.IndexName(idx)) {
+			c.Indices = append(c.Indices, idx)
+		}
+	}
+	return &c
+}
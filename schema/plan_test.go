@@ -0,0 +1,246 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+func ExamplePlanMigration() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	_, err := db.Exec(`
+		create table users (
+			id    int64 not null,
+			name  text,
+			age   int not null,
+			primary key (id)
+		);
+		create index users_age on users(age);
+		insert into users values (1, 'alice', 30), (2, null, 40);
+		create table obsolete (x);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	want := &Database{Tables: []*Table{
+		{
+			Name: "users",
+			Columns: []*Column{
+				{Name: "id", Type: Int64},
+				{Name: "name", Type: Text, Default: RawLiteral("''")},
+				{Name: "age", Type: Int},
+				{Name: "email", Type: Text, Nullable: true},
+			},
+			PK: []string{"id"},
+			Indices: []*Index{
+				{Name: "users_age", Columns: []IndexColumn{{Name: "age"}}},
+				{Columns: []IndexColumn{{Name: "email"}}, Unique: true},
+			},
+		},
+		{
+			Name:    "groups",
+			Columns: []*Column{{Name: "id", Type: Int64}},
+			PK:      []string{"id"},
+		},
+	}}
+
+	have, _ := Scan(db)
+	_, err = PlanMigration(have, want, NoRebuild)
+	fmt.Println(err)
+
+	stmts, _ := PlanMigration(have, want)
+	for _, s := range stmts {
+		fmt.Println(s)
+		if _, err := db.Exec(s); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	have, _ = Scan(db)
+	fmt.Println(Diff(have, want).Empty())
+
+	var name string
+	db.QueryRow("select name from users where id = 2").Scan(&name)
+	fmt.Printf("%q\n", name)
+
+	// Output:
+	// tables require rebuild: users
+	// create table "groups" (
+	//     id  int64  not null,
+	//     primary key (id)
+	// );
+	// create table new_users (
+	//     id     int64  not null,
+	//     name   text   not null default '',
+	//     age    int    not null,
+	//     email  text,
+	//     primary key (id)
+	// );
+	// insert into new_users (id, name, age) select id, coalesce(name, ''), age from users;
+	// drop table users;
+	// alter table new_users rename to users;
+	// create index users_age on users(age);
+	// create unique index users_email_index on users(email);
+	// pragma foreign_key_check(users);
+	// drop table obsolete;
+	// true
+	// ""
+}
+
+func ExamplePlanMigration_generated() {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table g (a int, b int as (a * 2), c int);
+		insert into g (a, c) values (1, 10), (2, 20);
+	`)
+
+	want := &Database{Tables: []*Table{{
+		Name: "g",
+		Columns: []*Column{
+			{Name: "a", Type: Int, Nullable: true},
+			{Name: "b", Type: Int, Nullable: true},
+			{Name: "c", Type: Int, Nullable: true, Generated: "a + 1"},
+		},
+	}}}
+
+	have, _ := Scan(db)
+	stmts, _ := PlanMigration(have, want)
+	for _, s := range stmts {
+		fmt.Println(s)
+		if _, err := db.Exec(s); err != nil {
+			fmt.Println(err)
+		}
+	}
+
+	rows, _ := db.Query("select a, b, c from g")
+	for rows.Next() {
+		var a, b, c int
+		rows.Scan(&a, &b, &c)
+		fmt.Println(a, b, c)
+	}
+
+	// Output:
+	// create table new_g (
+	//     a  int,
+	//     b  int,
+	//     c  int  generated always as (a + 1) virtual
+	// );
+	// insert into new_g (a, b) select a, b from g;
+	// drop table g;
+	// alter table new_g rename to g;
+	// pragma foreign_key_check(g);
+	// 1 2 2
+	// 2 4 3
+}
+
+func ExamplePlanMigration_notNull() {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table t (a text, x text);
+		insert into t values (null, 'column x');
+	`)
+
+	// string defaults are double quoted when scanned or loaded from yaml
+	want := &Database{Tables: []*Table{{
+		Name: "t",
+		Columns: []*Column{
+			{Name: "a", Type: Text, Default: RawLiteral(`"x"`)},
+			{Name: "x", Type: Text, Nullable: true},
+		},
+	}}}
+
+	have, _ := Scan(db)
+	stmts, _ := PlanMigration(have, want)
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			fmt.Println(err)
+		}
+	}
+	fmt.Println(stmts[1])
+
+	var a string
+	db.QueryRow("select a from t").Scan(&a)
+	fmt.Println(a)
+
+	// Output:
+	// insert into new_t (a, x) select coalesce(a, 'x'), x from t;
+	// x
+}
+
+func ExamplePlanMigration_quotedDefault() {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table t (a text, b text);
+		insert into t values (null, null);
+	`)
+
+	want := &Database{Tables: []*Table{{
+		Name: "t",
+		Columns: []*Column{
+			{Name: "a", Type: Text, Default: RawLiteral(`"it's"`)},
+			{Name: "b", Type: Text, Default: RawLiteral(`"say ""hi"""`)},
+		},
+	}}}
+
+	have, _ := Scan(db)
+	stmts, _ := PlanMigration(have, want)
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			fmt.Println(err)
+		}
+	}
+	fmt.Println(stmts[1])
+
+	var a, b string
+	db.QueryRow("select a, b from t").Scan(&a, &b)
+	fmt.Println(a, b)
+
+	// the scanned defaults match the model
+	have, _ = Scan(db)
+	fmt.Println(len(Diff(have, want).ChangedTables))
+
+	// Output:
+	// insert into new_t (a, b) select coalesce(a, 'it''s'), coalesce(b, 'say "hi"') from t;
+	// it's say "hi"
+	// 0
+}
+
+func ExamplePlanMigration_tempName() {
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.SetMaxOpenConns(1)
+	db.Exec(`
+		create table t (a int);
+		create table new_t (b int);
+		create index new_t_2 on new_t (b);
+		insert into t values (1);
+	`)
+
+	have, _ := Scan(db)
+	want, _ := Scan(db)
+	t, _ := want.FindTable("t")
+	t.Columns[0].Type = Text
+
+	stmts, _ := PlanMigration(have, want)
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			fmt.Println(err)
+		}
+	}
+	fmt.Println(stmts[0])
+
+	var n, a int
+	db.QueryRow("select count(*) from new_t").Scan(&n)
+	db.QueryRow("select a from t").Scan(&a)
+	fmt.Println(n, a)
+
+	// Output:
+	// create table new_t_3 (
+	//     a  text
+	// );
+	// 0 1
+}
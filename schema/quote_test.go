@@ -0,0 +1,70 @@
+package schema
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/adnsv/go-db3/internal/sqltext"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func ExampleQuoteName() {
+	for _, n := range []string{"id", "order", "first name", "x]y", `say "hi"`, "ключ", "2nd"} {
+		fmt.Println(QuoteName(n))
+	}
+
+	// Output:
+	// id
+	// "order"
+	// "first name"
+	// "x]y"
+	// "say ""hi"""
+	// "ключ"
+	// "2nd"
+}
+
+func FuzzQuoteName(f *testing.F) {
+	for _, n := range []string{"t", "order", "Group", "a b", "x]y", "[x]", `q"q`, "`q`", "'q'", "ключ", "1st", "a.b", "--c", "/*"} {
+		f.Add(n)
+	}
+
+	f.Fuzz(func(t *testing.T, name string) {
+		if name == "" || !utf8.ValidString(name) || strings.ContainsRune(name, 0) ||
+			strings.HasPrefix(strings.ToLower(name), "sqlite_") ||
+			strings.HasPrefix(name, "(") {
+			// index columns starting with '(' are expressions, see IndexColumn
+			t.Skip()
+		}
+
+		tt, err := sqltext.Tokenize(QuoteName(name))
+		if err != nil || len(tt) != 2 || !tt[0].IsName() || tt[0].Value() != name {
+			t.Fatalf("%q is quoted as %s", name, QuoteName(name))
+		}
+
+		want := &Database{Tables: []*Table{{
+			Name:    name,
+			Columns: []*Column{{Name: name, Type: Int}},
+			Indices: []*Index{{Columns: []IndexColumn{{Name: name, Desc: true}}}},
+			PK:      []string{name},
+		}}}
+		b := bytes.Buffer{}
+		want.CreateStatements(&b)
+		db, _ := sql.Open("sqlite3", ":memory:")
+		defer db.Close()
+		if _, err := db.Exec(b.String()); err != nil {
+			t.Fatalf("%s: %v", b.String(), err)
+		}
+
+		have, err := Scan(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d := Diff(have, want); !d.Empty() {
+			t.Fatalf("%s: %s", b.String(), d)
+		}
+	})
+}
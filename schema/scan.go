@@ -1,9 +1,12 @@
 package schema
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 
+	"github.com/adnsv/go-db3/internal/sqltext"
 	"golang.org/x/exp/slices"
 )
 
@@ -13,9 +16,21 @@ type Querier interface {
 	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
+// QuerierContext is a context-aware db query runner, typically should be
+// hooked to sql.Conn, sql.Tx or sql.DB.
+type QuerierContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // IsEmpty tests if a database has empty schema.
 func IsEmpty(src Querier) (bool, error) {
-	row := src.QueryRow("select count(*) from sqlite_master;")
+	return IsEmptyContext(context.Background(), with_context(src))
+}
+
+// IsEmptyContext tests if a database has empty schema.
+func IsEmptyContext(ctx context.Context, src QuerierContext) (bool, error) {
+	row := src.QueryRowContext(ctx, "select count(*) from sqlite_master;")
 	var n int
 	err := row.Scan(&n)
 	if err != nil {
@@ -26,24 +41,37 @@ func IsEmpty(src Querier) (bool, error) {
 
 // Scan obtains all the schema details from the sqlite database.
 func Scan(src Querier) (*Database, error) {
+	return ScanContext(context.Background(), with_context(src))
+}
+
+// ScanContext obtains all the schema details from the sqlite database. The
+// context is checked before each query and between rows.
+func ScanContext(ctx context.Context, src QuerierContext) (*Database, error) {
 	db := &Database{}
 
-	from_master := map[string]struct{}{}
-	err := query(src, "select name from sqlite_master where type='table'", nil,
+	from_master := map[string]string{}
+	index_sql := map[string]string{}
+	err := query(ctx, src, "select type, name, sql from sqlite_master where type in ('table', 'index')", nil,
 		func(row *sql.Rows) error {
+			var typ string
 			var n string
-			err := row.Scan(&n)
+			var s sql.NullString
+			err := row.Scan(&typ, &n, &s)
 			if err != nil {
 				return err
 			}
-			from_master[n] = struct{}{}
+			if typ == "table" {
+				from_master[n] = s.String
+			} else {
+				index_sql[n] = s.String
+			}
 			return nil
 		})
 	if err != nil {
 		return nil, err
 	}
 
-	err = query(src, "pragma table_list;", nil,
+	err = query(ctx, src, "pragma table_list;", nil,
 		func(row *sql.Rows) error {
 			var schema string
 			var name string
@@ -58,7 +86,8 @@ func Scan(src Querier) (*Database, error) {
 			if _, ok := from_master[name]; !ok {
 				return nil
 			}
-			if typ != "table" {
+			if typ != "table" || is_internal(name) {
+				// sqlite_sequence and sqlite_stat* are created by sqlite
 				return nil
 			}
 			db.Tables = append(db.Tables, &Table{
@@ -78,16 +107,19 @@ func Scan(src Querier) (*Database, error) {
 		}
 		pk_infos := []*pk_info{}
 
-		q := fmt.Sprintf("pragma table_info([%s])", table.Name)
-		err = query(src, q, nil, func(row *sql.Rows) error {
+		// table_xinfo also reports generated columns, hidden columns of
+		// virtual tables are skipped
+		q := fmt.Sprintf("pragma table_xinfo(%s)", QuoteName(table.Name))
+		err = query(ctx, src, q, nil, func(row *sql.Rows) error {
 			var cid int
 			var name string
 			var typeName string
 			var notnull int
 			var dfltValue sql.NullString
 			var pk int
-			err := row.Scan(&cid, &name, &typeName, &notnull, &dfltValue, &pk)
-			if err != nil {
+			var hidden int
+			err := row.Scan(&cid, &name, &typeName, &notnull, &dfltValue, &pk, &hidden)
+			if err != nil || hidden == 1 {
 				return err
 			}
 
@@ -96,7 +128,7 @@ func Scan(src Querier) (*Database, error) {
 			var dflt Literal
 
 			if dfltValue.Valid {
-				dflt = parseLiteral(dfltValue.String)
+				dflt = parseLiteral(default_text(dfltValue.String))
 			}
 
 			table.Columns = append(table.Columns, &Column{
@@ -104,6 +136,7 @@ func Scan(src Querier) (*Database, error) {
 				Type:     ColumnType(typeName),
 				Nullable: nullable,
 				Default:  dflt,
+				Stored:   hidden == 3,
 			})
 
 			if pk > 0 {
@@ -120,44 +153,338 @@ func Scan(src Querier) (*Database, error) {
 			table.PK = append(table.PK, info.n)
 		}
 
-		q = fmt.Sprintf("pragma index_list([%s])", table.Name)
-		err = query(src, q, nil, func(row *sql.Rows) error {
+		partial := map[*Index]bool{}
+		q = fmt.Sprintf("pragma index_list(%s)", QuoteName(table.Name))
+		err = query(ctx, src, q, nil, func(row *sql.Rows) error {
 			var seq int
 			var indexName string
 			var unique int
 			var origin string
-			var partial int
-			err := row.Scan(&seq, &indexName, &unique, &origin, &partial)
+			var partial_flag int
+			err := row.Scan(&seq, &indexName, &unique, &origin, &partial_flag)
 			if err != nil {
 				return err
 			}
 			index := &Index{
 				Name:    indexName,
 				Unique:  unique == 1,
-				Columns: []string{},
+				Columns: []IndexColumn{},
 			}
-			err = query(src, fmt.Sprintf("pragma index_info([%s])", indexName), nil, func(row *sql.Rows) error {
+			table.Indices = append(table.Indices, index)
+			partial[index] = partial_flag == 1
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// index columns are queried after the index list is closed, nesting
+		// queries would require a second connection
+		for _, index := range table.Indices {
+			q := fmt.Sprintf("pragma index_xinfo(%s)", QuoteName(index.Name))
+			expressions := false
+			err = query(ctx, src, q, nil, func(row *sql.Rows) error {
 				var seqno int
 				var cid int
-				var name string
-				err := row.Scan(&seqno, &cid, &name)
-				if err != nil {
+				var name sql.NullString
+				var desc int
+				var coll sql.NullString
+				var key int
+				err := row.Scan(&seqno, &cid, &name, &desc, &coll, &key)
+				if err != nil || key == 0 {
+					// auxiliary columns (rowid or primary key) are skipped
 					return err
 				}
-				index.Columns = append(index.Columns, name)
+				// expression columns come with cid -2 and are recovered
+				// from the sql below
+				expressions = expressions || cid == -2
+				c := IndexColumn{Name: name.String, Desc: desc == 1}
+				if !strings.EqualFold(coll.String, "binary") {
+					c.Collation = coll.String
+				}
+				index.Columns = append(index.Columns, c)
 				return nil
 			})
+			if err != nil {
+				return nil, err
+			}
+			if expressions || partial[index] {
+				if err = parse_index_sql(index, index_sql[index.Name]); err != nil {
+					return nil, fmt.Errorf("parsing index %s: %w", index.Name, err)
+				}
+			}
+		}
+
+		// details that are not reported by pragmas are recovered from the sql
+		ct, err := sqltext.ParseCreateTable(from_master[table.Name])
+		if err != nil {
+			ct = nil
+		}
+		scan_constraints(table, ct)
+
+		// autoincrement tables that had rows inserted are tracked in
+		// sqlite_sequence, this also covers the sql beyond the parser
+		if _, ok := from_master["sqlite_sequence"]; ok && !table.AutoIncrement && table.RowIDAlias() != nil {
+			err = query(ctx, src, "select 1 from sqlite_sequence where name = ?", []any{table.Name},
+				func(row *sql.Rows) error {
+					table.AutoIncrement = true
+					return nil
+				})
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		err = scan_foreign_keys(ctx, src, table, ct)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = scan_views_and_triggers(ctx, src, db)
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// scan_views_and_triggers collects views and triggers in their creation
+// order.
+func scan_views_and_triggers(ctx context.Context, src QuerierContext, db *Database) error {
+	err := query(ctx, src, "select type, name, tbl_name, sql from sqlite_master where type in ('view', 'trigger') order by rowid", nil,
+		func(row *sql.Rows) error {
+			var typ string
+			var name string
+			var table string
+			var s sql.NullString
+			err := row.Scan(&typ, &name, &table, &s)
 			if err != nil {
 				return err
 			}
-			table.Indices = append(table.Indices, index)
+			if typ == "view" {
+				db.Views = append(db.Views, &View{Name: name, SQL: s.String})
+			} else {
+				db.Triggers = append(db.Triggers, new_trigger(name, table, s.String))
+			}
+			return nil
+		})
+	if err != nil {
+		return err
+	}
+
+	for _, view := range db.Views {
+		q := fmt.Sprintf("pragma table_info(%s)", QuoteName(view.Name))
+		err = query(ctx, src, q, nil, func(row *sql.Rows) error {
+			var cid int
+			var name string
+			var typeName string
+			var notnull int
+			var dfltValue sql.NullString
+			var pk int
+			err := row.Scan(&cid, &name, &typeName, &notnull, &dfltValue, &pk)
+			if err != nil {
+				return err
+			}
+			view.Columns = append(view.Columns, &Column{
+				Name:     name,
+				Type:     ColumnType(typeName),
+				Nullable: notnull != 1,
+			})
 			return nil
 		})
 		if err != nil {
-			return nil, err
+			return err
 		}
 	}
-	return db, nil
+	return nil
+}
+
+// new_trigger produces the trigger description, timing and event are
+// recovered from the sql.
+func new_trigger(name, table, trigger_sql string) *Trigger {
+	t := &Trigger{Name: name, Table: table, SQL: trigger_sql}
+	if ct, err := sqltext.ParseCreateTrigger(trigger_sql); err == nil {
+		t.Timing = TriggerTiming(ct.Timing)
+		if t.Timing == "" {
+			t.Timing = Before
+		}
+		t.Event = TriggerEvent(ct.Event)
+		t.Columns = ct.Columns
+	}
+	return t
+}
+
+// default_text restores the parentheses around expression defaults, sqlite
+// strips them when reporting the default value.
+func default_text(s string) string {
+	tt, err := sqltext.Tokenize(s)
+	if err != nil {
+		return s
+	}
+	n := len(tt) - 1 // without EOF
+	if n <= 1 || (n == 2 && (tt[0].IsPunct("-") || tt[0].IsPunct("+")) && tt[1].Kind == sqltext.Number) {
+		return s
+	}
+	return parenthesized(s)
+}
+
+// parse_index_sql recovers the expression columns and the where clause of
+// the index from its sql. Without them the index would be recreated
+// differently, so the sql that can not be parsed is an error.
+func parse_index_sql(index *Index, index_sql string) error {
+	ci, err := sqltext.ParseCreateIndex(index_sql)
+	if err != nil {
+		return err
+	}
+	if len(ci.Columns) != len(index.Columns) {
+		return fmt.Errorf("expected %d columns, got %d", len(index.Columns), len(ci.Columns))
+	}
+	for i, c := range ci.Columns {
+		if c.Name == "" {
+			index.Columns[i].Name = parenthesized(c.Expr)
+		}
+	}
+	index.Where = ci.Where
+	return nil
+}
+
+// scan_constraints recovers check and unique constraints, collations,
+// conflict clauses and generated column expressions from the parsed table
+// definition.
+func scan_constraints(table *Table, ct *sqltext.CreateTable) {
+	if ct == nil {
+		return
+	}
+	for _, cd := range ct.Columns {
+		column, ok := table.FindColumn(cd.Name)
+		if !ok {
+			continue
+		}
+		checks := []string{}
+		for _, c := range cd.Constraints {
+			switch c.Kind {
+			case sqltext.NotNull:
+				column.OnConflict = parseConflictAction(c.OnConflict)
+			case sqltext.Collate:
+				column.Collate = c.Collation
+			case sqltext.Check:
+				checks = append(checks, c.Expr)
+			case sqltext.Generated:
+				column.Generated = c.Expr
+				column.Stored = c.Stored
+			case sqltext.PrimaryKey:
+				table.PKOnConflict = parseConflictAction(c.OnConflict)
+				table.AutoIncrement = c.AutoIncrement
+			case sqltext.Unique:
+				table.Uniques = append(table.Uniques, &UniqueConstraint{
+					Name:       c.Name,
+					Columns:    []string{column.Name},
+					OnConflict: parseConflictAction(c.OnConflict),
+				})
+			}
+		}
+		if len(checks) == 1 {
+			column.Check = checks[0]
+		} else if len(checks) > 1 {
+			column.Check = "(" + strings.Join(checks, ") and (") + ")"
+		}
+	}
+	for _, c := range ct.Constraints {
+		switch c.Kind {
+		case sqltext.PrimaryKey:
+			table.PKOnConflict = parseConflictAction(c.OnConflict)
+		case sqltext.Unique:
+			u := &UniqueConstraint{Name: c.Name, OnConflict: parseConflictAction(c.OnConflict)}
+			for _, ic := range c.Columns {
+				u.Columns = append(u.Columns, ic.Name)
+			}
+			table.Uniques = append(table.Uniques, u)
+		case sqltext.Check:
+			table.Checks = append(table.Checks, &CheckConstraint{Name: c.Name, Expr: c.Expr})
+		}
+	}
+}
+
+func scan_foreign_keys(ctx context.Context, src QuerierContext, table *Table, ct *sqltext.CreateTable) error {
+	fks := map[int]*ForeignKey{}
+	ids := []int{}
+	q := fmt.Sprintf("pragma foreign_key_list(%s)", QuoteName(table.Name))
+	err := query(ctx, src, q, nil, func(row *sql.Rows) error {
+		var id int
+		var seq int
+		var parent string
+		var from string
+		var to sql.NullString
+		var on_update string
+		var on_delete string
+		var match string
+		err := row.Scan(&id, &seq, &parent, &from, &to, &on_update, &on_delete, &match)
+		if err != nil {
+			return err
+		}
+		fk, ok := fks[id]
+		if !ok {
+			fk = &ForeignKey{
+				ParentTable: parent,
+				OnUpdate:    parseForeignKeyAction(on_update),
+				OnDelete:    parseForeignKeyAction(on_delete),
+			}
+			fks[id] = fk
+			ids = append(ids, id)
+		}
+		fk.Columns = append(fk.Columns, from)
+		if to.Valid {
+			fk.ParentColumns = append(fk.ParentColumns, to.String)
+		}
+		return nil
+	})
+	if err != nil || len(ids) == 0 {
+		return err
+	}
+
+	// sqlite enumerates foreign keys in reverse declaration order
+	slices.SortFunc(ids, func(a, b int) bool { return a > b })
+	for _, id := range ids {
+		table.ForeignKeys = append(table.ForeignKeys, fks[id])
+	}
+
+	// deferrability is not reported by the pragma, recover it from the sql
+	if ct != nil {
+		for _, fk := range table.ForeignKeys {
+			if r := find_references(ct, fk.Columns); r != nil {
+				fk.Deferrable = Deferrable(r.Deferrable)
+			}
+		}
+	}
+	return nil
+}
+
+// find_references locates the foreign key clause in the parsed table
+// definition by its child columns.
+func find_references(ct *sqltext.CreateTable, columns []string) *sqltext.References {
+	if len(columns) == 1 {
+		if cd := ct.Column(columns[0]); cd != nil {
+			if c := cd.Find(sqltext.ForeignKey); c != nil {
+				return c.References
+			}
+		}
+	}
+	for _, c := range ct.Constraints {
+		if c.Kind != sqltext.ForeignKey || len(c.Columns) != len(columns) {
+			continue
+		}
+		match := true
+		for i := range columns {
+			if !strings.EqualFold(c.Columns[i].Name, columns[i]) {
+				match = false
+				break
+			}
+		}
+		if match {
+			return c.References
+		}
+	}
+	return nil
 }
 
 // ValidateColumns checks table schema for presense of columns with the
@@ -235,27 +562,159 @@ func (table *Table) CheckIndices(required map[string]*Index) *ErrIndices {
 	}
 }
 
-// CompatibleTo returns true if both column signatures are compatible.
+// CompatibleTo returns true if both column signatures are compatible: the
+// columns have the same nullability, generated expression, collation, check
+// constraint and conflict clause.
 func (column *Column) CompatibleTo(info *Column) bool {
-	return column.Nullable == info.Nullable
+	return column.Nullable == info.Nullable &&
+		same_expr(column.Generated, info.Generated) && column.Stored == info.Stored &&
+		strings.EqualFold(collation(column.Collate), collation(info.Collate)) &&
+		same_expr(column.Check, info.Check) &&
+		same_conflict(column.OnConflict, info.OnConflict)
 }
 
+// CompatibleTo returns true if both constraints cover the same columns with
+// the same conflict clause, constraint names are not compared.
+func (u *UniqueConstraint) CompatibleTo(other *UniqueConstraint) bool {
+	return slices.Equal(u.Columns, other.Columns) &&
+		same_conflict(u.OnConflict, other.OnConflict)
+}
+
+// CompatibleTo returns true if both constraints have the same expression,
+// constraint names are not compared.
+func (c *CheckConstraint) CompatibleTo(other *CheckConstraint) bool {
+	return same_expr(c.Expr, other.Expr)
+}
+
+func same_conflict(a, b ConflictAction) bool {
+	return a == b || (a == "" && b == Abort) || (a == Abort && b == "")
+}
+
+// CheckForeignKeys validates if database foreign keys match the foreign keys
+// specified in the data model. Foreign keys are identified by their child
+// columns.
+func (table *Table) CheckForeignKeys(required []*ForeignKey) *ErrForeignKeys {
+	err := &ErrForeignKeys{}
+	for _, want := range required {
+		if have, exists := table.FindForeignKey(want.Columns...); !exists {
+			err.Missing = append(err.Missing, want.String())
+		} else if !have.CompatibleTo(want) {
+			err.Incompatible = append(err.Incompatible, want.String())
+		}
+	}
+	if len(err.Missing) > 0 || len(err.Incompatible) > 0 {
+		return err
+	} else {
+		return nil
+	}
+}
+
+// CompatibleTo returns true if both indices have the same uniqueness, columns
+// and where clause. Expressions are compared disregarding whitespace and the
+// case of keywords and identifiers.
 func (idx *Index) CompatibleTo(other *Index) bool {
 	return idx.Unique == other.Unique &&
-		slices.Equal(idx.Columns, other.Columns)
+		slices.EqualFunc(idx.Columns, other.Columns, func(a, b IndexColumn) bool {
+			return a.CompatibleTo(b)
+		}) &&
+		same_expr(idx.Where, other.Where)
+}
+
+// CompatibleTo returns true if both entries refer to the same column or
+// expression with the same collation and sort order.
+func (c IndexColumn) CompatibleTo(other IndexColumn) bool {
+	if c.Desc != other.Desc || !strings.EqualFold(collation(c.Collation), collation(other.Collation)) {
+		return false
+	}
+	if c.IsExpression() || other.IsExpression() {
+		return same_expr(c.Name, other.Name)
+	}
+	return c.Name == other.Name
+}
+
+func collation(s string) string {
+	if s == "" {
+		return "binary"
+	}
+	return s
+}
+
+// same_expr compares sql expressions token by token.
+func same_expr(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ta, err := sqltext.Tokenize(a)
+	if err != nil {
+		return false
+	}
+	tb, err := sqltext.Tokenize(b)
+	if err != nil || len(ta) != len(tb) {
+		return false
+	}
+	for i := range ta {
+		if is_name_token(ta[i]) && is_name_token(tb[i]) {
+			if !strings.EqualFold(ta[i].Value(), tb[i].Value()) {
+				return false
+			}
+		} else if ta[i].Kind != tb[i].Kind || ta[i].Text != tb[i].Text {
+			return false
+		}
+	}
+	return true
 }
 
-func query(src Querier, q string, args []any, on_row func(row *sql.Rows) error) error {
-	rows, err := src.Query(q, args...)
+func is_name_token(t sqltext.Token) bool {
+	return t.Kind == sqltext.Ident || t.Kind == sqltext.Quoted
+}
+
+// CompatibleTo returns true if both foreign keys reference the same parent
+// columns with the same actions.
+func (fk *ForeignKey) CompatibleTo(other *ForeignKey) bool {
+	return slices.Equal(fk.Columns, other.Columns) &&
+		strings.EqualFold(fk.ParentTable, other.ParentTable) &&
+		slices.Equal(fk.ParentColumns, other.ParentColumns) &&
+		fk.OnUpdate == other.OnUpdate &&
+		fk.OnDelete == other.OnDelete &&
+		fk.Deferrable == other.Deferrable
+}
+
+func query(ctx context.Context, src QuerierContext, q string, args []any, on_row func(row *sql.Rows) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	rows, err := src.QueryContext(ctx, q, args...)
 	if err != nil {
 		return err
 	}
 	defer rows.Close()
 	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
 		err = on_row(rows)
 		if err != nil {
 			return err
 		}
 	}
-	return nil
+	return rows.Err()
+}
+
+// with_context adapts Querier to QuerierContext, the context is not passed
+// to the underlying queries.
+func with_context(src Querier) QuerierContext {
+	if c, ok := src.(QuerierContext); ok {
+		return c
+	}
+	return &querier_adapter{src}
+}
+
+type querier_adapter struct{ src Querier }
+
+func (a *querier_adapter) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return a.src.Query(query, args...)
+}
+
+func (a *querier_adapter) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return a.src.QueryRow(query, args...)
 }
@@ -1,6 +1,8 @@
 package schema
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"fmt"
 
@@ -65,6 +67,446 @@ func ExampleDatabase() {
 	//     - {name: s8, type: TEXT}
 	//     - {name: f1, type: timestamp, default: CURRENT_TIMESTAMP}
 	//   indices:
-	//     - {name: sqlite_autoindex_t_1, unique: true}
+	//     - {name: sqlite_autoindex_t_1, unique: true, columns: [id]}
+	//   uniques:
+	//     - {columns: [id]}
 
 }
+
+func ExampleScan_indices() {
+
+	// every connection to :memory: opens a separate database, index columns
+	// are only found if Scan does not nest queries
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table a (x int, y int);
+		create index a_x on a(x);
+		create index a_xy on a(x, y desc);
+		create table b (z text unique);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+	for _, t := range dbsch.Tables {
+		for _, idx := range t.Indices {
+			fmt.Println(t.Name, idx.Name, idx.Columns)
+		}
+	}
+
+	// Output:
+	// b sqlite_autoindex_b_1 [z]
+	// a a_xy [x y desc]
+	// a a_x [x]
+}
+
+func ExampleScan_foreignKeys() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table p (
+			id int not null primary key,
+			a  int,
+			b  int,
+			unique (a, b)
+		);
+		create table c (
+			x int references p(id) on delete cascade deferrable initially immediate,
+			pa int,
+			pb int,
+			foreign key (pa, pb) references p(a, b) on update set null deferrable initially deferred
+		);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	c, _ := dbsch.FindTable("c")
+	y, _ := yaml.Marshal(c.ForeignKeys)
+	fmt.Print(string(y))
+
+	back := []*ForeignKey{}
+	yaml.Unmarshal(y, &back)
+	fmt.Println(c.CheckForeignKeys(back) == nil)
+	fmt.Println(c.CheckForeignKeys([]*ForeignKey{
+		{Columns: []string{"x"}, ParentTable: "p", ParentColumns: []string{"id"}},
+		{Columns: []string{"w"}, ParentTable: "p"},
+	}))
+
+	b := bytes.Buffer{}
+	c.CreateStatements(&b)
+	fmt.Print(b.String())
+
+	// Output:
+	// - {columns: [x], parent_table: p, parent_columns: [id], on_delete: cascade, deferrable: immediate}
+	// - {columns: [pa, pb], parent_table: p, parent_columns: [a, b], on_update: set null, deferrable: deferred}
+	// true
+	// incompatible table foreign keys, missing foreign keys: (w) -> p(), incompatible foreign keys: (x) -> p(id)
+	// create table c (
+	//     x   INT,
+	//     pa  INT,
+	//     pb  INT,
+	//     foreign key (x) references p(id) on delete cascade deferrable initially immediate,
+	//     foreign key (pa,pb) references p(a,b) on update set null deferrable initially deferred
+	// );
+}
+
+func ExampleScanContext() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	db.Exec(`create table t (n int)`)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := ScanContext(ctx, db)
+	fmt.Println(err)
+
+	// Output:
+	// context canceled
+}
+
+func ExampleScan_viewsAndTriggers() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (id int not null primary key, title text, updated int);
+		create view v as select id, title from t where id > 0;
+		create trigger t_touch after update of title on t begin
+			update t set updated = 1 where id = new.id;
+		end;
+		create trigger v_insert instead of insert on v begin
+			insert into t (id, title) values (new.id, new.title);
+		end;
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	y, _ := yaml.Marshal(dbsch.Views)
+	fmt.Print(string(y))
+	for _, t := range dbsch.Triggers {
+		fmt.Println(t.Name, t.Table, t.Timing, t.Event, t.Columns)
+	}
+
+	// Output:
+	// - view: v
+	//   sql: CREATE VIEW v as select id, title from t where id > 0
+	//   columns:
+	//     - {name: id, type: INT, nullable: true}
+	//     - {name: title, type: TEXT, nullable: true}
+	// t_touch t after update [title]
+	// v_insert v instead of insert []
+}
+
+func ExampleScan_partialIndices() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (id int not null primary key, name text, deleted int);
+		create unique index t_name on t(lower(name), id) where deleted is null;
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	t, _ := dbsch.FindTable("t")
+	y, _ := yaml.Marshal(t.Indices)
+	fmt.Print(string(y))
+
+	back := []*Index{}
+	yaml.Unmarshal(y, &back)
+	fmt.Println(back[0].CompatibleTo(t.Indices[0]))
+
+	fmt.Println(t.CheckIndices(map[string]*Index{
+		"t_name": {Unique: true, Columns: []IndexColumn{{Name: "(LOWER(name))"}, {Name: "id"}}, Where: "deleted IS NULL"},
+	}) == nil)
+	fmt.Println(t.CheckIndices(map[string]*Index{
+		"t_name": {Unique: true, Columns: []IndexColumn{{Name: "(lower(name))"}, {Name: "id"}}},
+	}))
+
+	fmt.Println(t.CreateIndexStatement(&Index{Columns: []IndexColumn{{Name: "(lower(name))"}}, Where: "deleted is null"}))
+
+	// Output:
+	// - {name: t_name, unique: true, columns: [(lower(name)), id], where: deleted is null}
+	// - {name: sqlite_autoindex_t_1, unique: true, columns: [id]}
+	// true
+	// true
+	// incompatible table indices, incompatible indices: t_name
+	// create index t_expr1_index on t((lower(name))) where deleted is null;
+}
+
+func ExampleScan_unparsedIndex() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (a text, b text);
+		create index t_ab on t(a collate nocase collate binary, lower(b)) where b > '';
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	// the sql of t_ab is beyond the parser, the expression column and the
+	// where clause can not be recovered
+	_, err = Scan(db)
+	fmt.Println(err)
+
+	// Output:
+	// parsing index t_ab: expected ')', got 'collate' at offset 40
+}
+
+func ExampleScan_defaults() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (
+			a int default 1,
+			b int default -1,
+			c text default 'x',
+			d text default current_timestamp,
+			e real default (0.5 * 2),
+			f text default (lower('X'))
+		);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	// sqlite reports expression defaults without the parentheses, Scan
+	// restores them so that the default can be declared again
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	t, _ := dbsch.FindTable("t")
+	for _, c := range t.Columns {
+		fmt.Println(c.Name, c.Default.SQLLiteral())
+	}
+
+	// Output:
+	// a 1
+	// b -1
+	// c "x"
+	// d CURRENT_TIMESTAMP
+	// e (0.5 * 2)
+	// f (lower('X'))
+}
+
+func ExampleScan_indexColumnOrder() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (id int not null primary key, name text, created int);
+		create index t_name on t(name collate nocase, created desc);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	t, _ := dbsch.FindTable("t")
+	idx, _ := t.FindIndex("t_name")
+	y, _ := yaml.Marshal(idx)
+	fmt.Print(string(y))
+
+	back := &Index{}
+	yaml.Unmarshal(y, back)
+	fmt.Println(back.CompatibleTo(idx), back.Columns[0].Collation, back.Columns[1].Desc)
+	fmt.Println(t.CreateIndexStatement(idx))
+
+	// Output:
+	// {name: t_name, columns: [name collate nocase, created desc]}
+	// true nocase true
+	// create index t_name on t(name collate nocase,created desc);
+}
+
+func ExampleScan_constraints() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (
+			id    int not null primary key on conflict replace,
+			name  text not null on conflict ignore collate nocase check (length(name) > 0),
+			a     int,
+			b     int,
+			constraint ab unique (a, b) on conflict fail,
+			check (a < b)
+		);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	t, _ := dbsch.FindTable("t")
+	t.Indices = nil
+	y, _ := yaml.Marshal(t)
+	fmt.Print(string(y))
+
+	name, _ := t.FindColumn("name")
+	fmt.Println(name.CompatibleTo(&Column{Name: "name", Collate: "NOCASE", Check: "LENGTH(name)>0", OnConflict: Ignore}))
+	fmt.Println(name.CompatibleTo(&Column{Name: "name", Check: "length(name) > 0", OnConflict: Ignore}))
+
+	b := bytes.Buffer{}
+	t.CreateStatements(&b)
+	fmt.Print(b.String())
+
+	// Output:
+	// table: t
+	// columns:
+	//     - {name: id, type: INT}
+	//     - {name: name, type: TEXT, collate: nocase, check: length(name) > 0, on_conflict: ignore}
+	//     - {name: a, type: INT, nullable: true}
+	//     - {name: b, type: INT, nullable: true}
+	// pk: [id]
+	// pk_on_conflict: replace
+	// uniques:
+	//     - {name: ab, columns: [a, b], on_conflict: fail}
+	// checks:
+	//     - {expr: a < b}
+	// true
+	// false
+	// create table t (
+	//     id    INT   not null,
+	//     name  TEXT  not null on conflict ignore collate nocase check (length(name) > 0),
+	//     a     INT,
+	//     b     INT,
+	//     primary key (id) on conflict replace,
+	//     constraint ab unique (a,b) on conflict fail,
+	//     check (a < b)
+	// );
+}
+
+func ExampleScan_generatedColumns() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table t (
+			a  int not null,
+			b  int generated always as (a * 2) virtual,
+			c  text as (upper(cast(a as text))) stored
+		);
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	t, _ := dbsch.FindTable("t")
+	y, _ := yaml.Marshal(t.Columns)
+	fmt.Print(string(y))
+
+	b := bytes.Buffer{}
+	t.CreateStatements(&b)
+	fmt.Print(b.String())
+
+	// Output:
+	// - {name: a, type: INT}
+	// - {name: b, type: INT, nullable: true, generated: a * 2}
+	// - {name: c, type: TEXT, nullable: true, generated: upper(cast(a as text)), stored: true}
+	// create table t (
+	//     a  INT   not null,
+	//     b  INT   generated always as (a * 2) virtual,
+	//     c  TEXT  generated always as (upper(cast(a as text))) stored
+	// );
+}
+
+func ExampleScan_autoIncrement() {
+
+	db, _ := sql.Open("sqlite3", ":memory:")
+	_, err := db.Exec(`
+		create table a (id integer not null, name text, primary key (id));
+		create table b (id integer primary key autoincrement, name text);
+		create table c (id int primary key, name text);
+		create table d (id integer primary key autoincrement, name text constraint n);
+		insert into b (name) values ('x');
+		insert into d (name) values ('x');
+	`)
+	if err != nil {
+		fmt.Print(err)
+	}
+
+	dbsch, err := Scan(db)
+	if err != nil {
+		fmt.Print(err)
+		return
+	}
+
+	fmt.Println(len(dbsch.Tables), dbsch.HasTable("sqlite_sequence"))
+
+	b := bytes.Buffer{}
+	// the sql of d is beyond the parser, autoincrement is recovered from
+	// sqlite_sequence
+	for _, n := range []string{"a", "b", "c", "d"} {
+		t, _ := dbsch.FindTable(n)
+		fmt.Println(n, t.RowIDAlias() != nil, t.AutoIncrement)
+		t.CreateStatements(&b)
+	}
+	fmt.Print(b.String())
+
+	// Output:
+	// 4 false
+	// a true false
+	// b true true
+	// c false false
+	// d true true
+	// create table a (
+	//     id    INTEGER  not null primary key,
+	//     name  TEXT
+	// );
+	// create table b (
+	//     id    INTEGER  primary key autoincrement,
+	//     name  TEXT
+	// );
+	// create table c (
+	//     id    INT,
+	//     name  TEXT,
+	//     primary key (id)
+	// );
+	// create unique index sqlite_autoindex_c_1 on c(id);
+	// create table d (
+	//     id    INTEGER  primary key autoincrement,
+	//     name  TEXT
+	// );
+}